@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// apiKeyPrefix starts every API key so that leaked keys are easy to spot.
+// A key reads lk_<id>_<secret>: the id is stored in plain text to look the
+// key up, the whole key only as a SHA-256 hash.
+const apiKeyPrefix = "lk_"
+
+// NewAPIKey generates a key and returns it with the prefix and hash to store.
+// The key itself cannot be recovered later.
+func NewAPIKey() (key, prefix string, hash []byte, err error) {
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", nil, err
+	}
+	prefix = apiKeyPrefix + hex.EncodeToString(id)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, hashAPIKey(key), nil
+}
+
+// parseAPIKey returns the stored prefix of key.
+func parseAPIKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(key[len(apiKeyPrefix):], "_")
+	if !ok || id == "" || secret == "" {
+		return "", false
+	}
+	return apiKeyPrefix + id, true
+}
+
+func hashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+func matchAPIKey(key string, hash []byte) bool {
+	return subtle.ConstantTimeCompare(hashAPIKey(key), hash) == 1
+}
@@ -0,0 +1,105 @@
+// Package auth authenticates API callers. Machine clients send an API key
+// whose hash is kept in an APIKeyStore; other callers send a JWT issued by an
+// identity provider, signed with a shared HS256 secret or an RS256 key.
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"lesson/storage"
+)
+
+// Method names how a principal authenticated. MethodNone describes the
+// anonymous caller of a server that runs without authentication.
+type Method string
+
+const (
+	MethodAPIKey Method = "api_key"
+	MethodJWT    Method = "jwt"
+	MethodNone   Method = "none"
+)
+
+// Principal is the authenticated caller of a request. Subject is the JWT sub
+// claim, or "api_key:<id>" for API keys, which also set KeyID and Name. Roles
+// come from the API key or the roles claim of the JWT; Permissions are the
+// ones they grant.
+type Principal struct {
+	Subject     string
+	Method      Method
+	KeyID       int
+	Name        string
+	Roles       []string
+	Permissions []Permission
+}
+
+// Authenticator checks the credentials of a request and resolves the
+// permissions of the caller.
+type Authenticator struct {
+	keys  storage.APIKeyStore
+	roles storage.RoleStore
+	jwt   *JWTVerifier
+}
+
+// NewAuthenticator returns an Authenticator that looks API keys up in keys,
+// verifies bearer tokens with verifier and resolves roles with roles. A nil
+// verifier rejects every token.
+func NewAuthenticator(keys storage.APIKeyStore, roles storage.RoleStore, verifier *JWTVerifier) *Authenticator {
+	return &Authenticator{keys: keys, roles: roles, jwt: verifier}
+}
+
+// APIKey authenticates a plaintext API key and records that it was used.
+func (a *Authenticator) APIKey(ctx context.Context, key string) (Principal, error) {
+	prefix, ok := parseAPIKey(key)
+	if !ok {
+		return Principal{}, storage.UnauthenticatedError("invalid API key")
+	}
+	stored, err := a.keys.GetAPIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Principal{}, storage.UnauthenticatedError("invalid API key")
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	if stored.RevokedAt != nil || !matchAPIKey(key, stored.Hash) {
+		return Principal{}, storage.UnauthenticatedError("invalid API key")
+	}
+	if err := a.keys.TouchAPIKey(ctx, stored.ID); err != nil {
+		// A stale last-used time is no reason to turn the caller away.
+		slog.Warn("recording API key use failed", "key_id", stored.ID, "error", err)
+	}
+	return a.authorize(ctx, Principal{
+		Subject: "api_key:" + strconv.Itoa(stored.ID),
+		Method:  MethodAPIKey,
+		KeyID:   stored.ID,
+		Name:    stored.Name,
+		Roles:   []string{stored.Role},
+	})
+}
+
+// Bearer authenticates a JWT.
+func (a *Authenticator) Bearer(ctx context.Context, token string) (Principal, error) {
+	if a.jwt == nil {
+		return Principal{}, storage.UnauthenticatedError("bearer tokens are not accepted")
+	}
+	principal, err := a.jwt.Verify(token)
+	if err != nil {
+		return Principal{}, err
+	}
+	return a.authorize(ctx, principal)
+}
+
+// authorize sets the permissions granted by the roles of principal.
+func (a *Authenticator) authorize(ctx context.Context, principal Principal) (Principal, error) {
+	permissions, err := a.roles.GetRolePermissions(ctx, principal.Roles)
+	if err != nil {
+		return Principal{}, err
+	}
+	principal.Permissions = make([]Permission, len(permissions))
+	for i, permission := range permissions {
+		principal.Permissions[i] = Permission(permission)
+	}
+	return principal, nil
+}
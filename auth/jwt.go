@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"lesson/storage"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTVerifier checks bearer tokens. It accepts HS256 tokens when it has a
+// secret and RS256 tokens when it has a public key. Tokens must carry exp and
+// sub; iss and aud are checked when configured. The roles claim, a list of
+// role names, decides what the caller may do.
+type JWTVerifier struct {
+	secret    []byte
+	publicKey *rsa.PublicKey
+	parser    *jwt.Parser
+}
+
+// NewJWTVerifier returns a verifier for tokens signed with secret or for
+// publicKey, either of which may be empty. Empty issuer or audience skip the
+// respective check.
+func NewJWTVerifier(secret []byte, publicKey *rsa.PublicKey, issuer, audience string) (*JWTVerifier, error) {
+	var methods []string
+	if len(secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if publicKey != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("neither a secret nor a public key is configured")
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+	return &JWTVerifier{secret: secret, publicKey: publicKey, parser: jwt.NewParser(options...)}, nil
+}
+
+// claims are the claims of a bearer token the verifier reads.
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+}
+
+// Verify checks token and returns the principal named by its sub claim, with
+// the roles of its roles claim.
+func (v *JWTVerifier) Verify(token string) (Principal, error) {
+	var claims claims
+	parsed, err := v.parser.ParseWithClaims(token, &claims, v.key)
+	if err != nil {
+		return Principal{}, storage.UnauthenticatedError(tokenMessage(err))
+	}
+	subject, err := parsed.Claims.GetSubject()
+	if err != nil || subject == "" {
+		return Principal{}, storage.UnauthenticatedError("invalid bearer token: sub claim is required")
+	}
+	return Principal{Subject: subject, Method: MethodJWT, Roles: claims.Roles}, nil
+}
+
+func (v *JWTVerifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return v.secret, nil
+	case *jwt.SigningMethodRSA:
+		return v.publicKey, nil
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
+
+// tokenMessage says why a token was rejected without echoing its content.
+func tokenMessage(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "bearer token has expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return "bearer token is not valid yet"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "bearer token has an unexpected issuer"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "bearer token has an unexpected audience"
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return "bearer token lacks a required claim"
+	}
+	return "invalid bearer token"
+}
@@ -0,0 +1,33 @@
+package auth
+
+// Permission allows one kind of operation. Roles, stored in the database,
+// grant sets of permissions; SetupRouter declares the permission each route
+// needs.
+type Permission string
+
+const (
+	CustomersRead   Permission = "customers:read"
+	CustomersWrite  Permission = "customers:write"
+	CustomersDelete Permission = "customers:delete"
+
+	ItemsRead   Permission = "items:read"
+	ItemsWrite  Permission = "items:write"
+	ItemsDelete Permission = "items:delete"
+
+	TransactionsRead   Permission = "transactions:read"
+	TransactionsCreate Permission = "transactions:create"
+	TransactionsWrite  Permission = "transactions:write"
+	TransactionsVoid   Permission = "transactions:void"
+
+	APIKeysManage Permission = "api_keys:manage"
+)
+
+// Can reports whether p grants permission.
+func (p Principal) Can(permission Permission) bool {
+	for _, granted := range p.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"lesson/auth"
+	"lesson/config"
+	"lesson/storage"
+)
+
+// bootstrapAPIKey creates an admin API key in a fresh in-memory store and
+// prints it, since the apikey command only reaches the database and nothing
+// else could create the first key.
+func bootstrapAPIKey(store storage.APIKeyStore) error {
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return err
+	}
+	createdKey, err := store.CreateAPIKey(context.Background(), storage.APIKey{Name: "bootstrap", Role: "admin", Prefix: prefix, Hash: hash})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Created %s API key %d (%s) for the in-memory store. It is shown only once:\n%s\n", createdKey.Role, createdKey.ID, createdKey.Name, key)
+	return nil
+}
+
+// runAPIKey manages API keys directly in the database, which is how the first
+// key is created before anyone can call POST /v2/api-keys.
+func runAPIKey(cfg config.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing apikey command: create ROLE NAME, list or revoke ID")
+	}
+
+	conn, err := storage.InitDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := checkSchema(conn, false); err != nil {
+		return err
+	}
+	store := storage.NewPostgresStore(conn, 0)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		if len(args) < 3 {
+			return errors.New("apikey create needs a role and a name, e.g. apikey create admin ops")
+		}
+		role, name := args[1], strings.TrimSpace(strings.Join(args[2:], " "))
+		key, prefix, hash, err := auth.NewAPIKey()
+		if err != nil {
+			return err
+		}
+		createdKey, err := store.CreateAPIKey(ctx, storage.APIKey{Name: name, Role: role, Prefix: prefix, Hash: hash})
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("unknown role %q", role)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s API key %d (%s). It is shown only once:\n%s\n", createdKey.Role, createdKey.ID, createdKey.Name, key)
+		return nil
+	case "list":
+		keys, err := store.GetAPIKeys(ctx, true)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			state := "active"
+			if key.RevokedAt != nil {
+				state = "revoked " + formatTime(key.RevokedAt, "")
+			}
+			fmt.Printf("%d\t%s\t%s\t%s\tlast used %s\t%s\n", key.ID, key.Prefix, key.Role, key.Name, formatTime(key.LastUsedAt, "never"), state)
+		}
+		return nil
+	case "revoke":
+		if len(args) < 2 {
+			return errors.New("apikey revoke needs an ID")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid ID %q", args[1])
+		}
+		if _, err := store.RevokeAPIKey(ctx, id); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked API key %d\n", id)
+		return nil
+	}
+	return fmt.Errorf("unknown apikey command %q", args[0])
+}
+
+func formatTime(t *time.Time, zero string) string {
+	if t == nil {
+		return zero
+	}
+	return t.Format(time.RFC3339)
+}
@@ -1,23 +1,161 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
+	"time"
 
+	"lesson/config"
 	api "lesson/handlers"
 	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
 )
 
+const usage = `Usage:
+  %[1]s [flags]                          serve the HTTP API
+  %[1]s [flags] migrate up               apply all pending migrations
+  %[1]s [flags] migrate down             roll back the last applied migration
+  %[1]s [flags] migrate status           list migrations and whether they are applied
+  %[1]s [flags] migrate to N             migrate up or down to version N (0 rolls back everything)
+  %[1]s [flags] spec check               fail if the routes and the OpenAPI spec in docs disagree
+  %[1]s [flags] apikey create ROLE NAME  create an API key with a role (cashier, manager, admin) and print it once
+  %[1]s [flags] apikey list              list API keys, including revoked ones
+  %[1]s [flags] apikey revoke ID         revoke an API key
+
+Flags override APP_* environment variables, which override the config file.
+
+Flags:
+`
+
+//go:generate go run github.com/swaggo/swag/cmd/swag@v1.16.3 init --dir .. --generalInfo cmd/main.go --output ../docs --parseDependency
+
+// @title Lesson shop API
+// @version 2.0
+// @description Customers, items and the transactions between them. The /v1 routes are deprecated in favour of /v2.
+// @BasePath /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key created with POST /v2/api-keys or the apikey subcommand.
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT issued by the identity provider, sent as "Bearer <token>".
 func main() {
-	db, err := storage.InitDB()
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), usage, filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	cfg, err := config.Load(fs, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatal("Error initializing database:", err)
+		log.Fatal("Invalid configuration: ", err)
 	}
-	defer db.Close()
+	setupLogging(cfg)
+	fmt.Fprintf(os.Stderr, "Effective configuration:\n%s", cfg)
 
-	r := api.SetupRouter(db)
+	if fs.Arg(0) == "migrate" {
+		if err := runMigrate(cfg.DB, fs.Args()[1:]); err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		return
+	}
+	if fs.Arg(0) == "spec" {
+		if err := runSpec(cfg, fs.Args()[1:]); err != nil {
+			log.Fatal("Spec check failed: ", err)
+		}
+		return
+	}
+	if fs.Arg(0) == "apikey" {
+		if err := runAPIKey(cfg.DB, fs.Args()[1:]); err != nil {
+			log.Fatal("API key command failed: ", err)
+		}
+		return
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
 
-	if err := http.ListenAndServe(":8080", r); err != nil {
+	var store interface {
+		storage.CustomerStore
+		storage.ItemStore
+		storage.TransactionStore
+		storage.OrderStore
+		storage.BalanceStore
+		storage.StockStore
+		storage.APIKeyStore
+		storage.RoleStore
+		storage.IdempotencyStore
+	}
+	if cfg.Memory {
+		log.Println("Using in-memory store")
+		store = storage.NewMemoryStore()
+		if cfg.Auth.Enabled {
+			if err := bootstrapAPIKey(store); err != nil {
+				log.Fatal("Error creating the bootstrap API key: ", err)
+			}
+		}
+	} else {
+		conn, err := storage.InitDB(cfg.DB)
+		if err != nil {
+			log.Fatal("Error initializing database:", err)
+		}
+		defer conn.Close()
+
+		if err := checkSchema(conn, cfg.AutoMigrate); err != nil {
+			log.Fatal(err)
+		}
+		store = storage.NewPostgresStore(conn, cfg.Query.SlowThreshold.Duration())
+	}
+
+	r := api.SetupRouter(cfg, store, store, store, store, store, store, store, store, store)
+	go expireIdempotencyKeys(store, cfg.Idempotency.CleanupInterval.Duration())
+
+	server := &http.Server{
+		Addr:         cfg.HTTP.Addr,
+		Handler:      r,
+		ReadTimeout:  cfg.HTTP.ReadTimeout.Duration(),
+		WriteTimeout: cfg.HTTP.WriteTimeout.Duration(),
+		IdleTimeout:  cfg.HTTP.IdleTimeout.Duration(),
+	}
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatal("Error starting server:", err)
 	}
 }
+
+// expireIdempotencyKeys deletes expired idempotency keys every interval for
+// as long as the server runs.
+func expireIdempotencyKeys(store storage.IdempotencyStore, interval time.Duration) {
+	for range time.Tick(interval) {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		deleted, err := store.DeleteExpiredIdempotencyKeys(ctx)
+		cancel()
+		if err != nil {
+			slog.Warn("deleting expired idempotency keys failed", "error", err)
+			continue
+		}
+		slog.Debug("deleted expired idempotency keys", "count", deleted)
+	}
+}
+
+// setupLogging routes the standard logger through slog at the configured
+// level and only lets gin print its route table in debug mode.
+func setupLogging(cfg config.Config) {
+	level, _ := cfg.Level()
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	if level > slog.LevelDebug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"lesson/config"
+	"lesson/db"
+	"lesson/storage"
+)
+
+func runMigrate(cfg config.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing migrate command: up, down, status or to N")
+	}
+
+	conn, err := storage.InitDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down()
+	case "to":
+		if len(args) < 2 {
+			return errors.New("migrate to needs a version")
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = migrator.To(version)
+	case "status":
+		return printStatus(migrator)
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	log.Printf("Schema is at version %d", version)
+	return nil
+}
+
+func printStatus(migrator *db.Migrator) error {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied"
+		}
+		fmt.Printf("%06d_%s\t%s\n", status.Version, status.Name, state)
+	}
+	return nil
+}
+
+// checkSchema refuses to serve when the database schema is older than the
+// migrations embedded in the binary, applying them first if autoMigrate is set.
+func checkSchema(conn *sql.DB, autoMigrate bool) error {
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+	if autoMigrate {
+		if err := migrator.Up(); err != nil {
+			return fmt.Errorf("auto-migrate: %w", err)
+		}
+	}
+
+	version, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	if latest := db.LatestVersion(); version < latest {
+		return fmt.Errorf("database schema is at version %d but the code needs %d: run \"migrate up\" or start with -auto-migrate", version, latest)
+	}
+	return nil
+}
@@ -0,0 +1,403 @@
+// Package config loads the service configuration.
+//
+// Values are resolved in increasing order of precedence:
+//
+//  1. built-in defaults,
+//  2. the config file given by -config or APP_CONFIG (YAML, or TOML when the
+//     file name ends in .toml),
+//  3. APP_* environment variables,
+//  4. command line flags.
+//
+// The database password may be read from a file with db.password_file
+// (APP_DB_PASSWORD_FILE, -db-password-file), which is convenient for
+// container secrets, and so may the JWT keys with auth.jwt.secret_file and
+// auth.jwt.public_key_file.
+package config
+
+import (
+	"crypto/rsa"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "APP_"
+
+type Config struct {
+	HTTP        HTTP        `yaml:"http" toml:"http"`
+	DB          DB          `yaml:"db" toml:"db"`
+	Query       Query       `yaml:"query" toml:"query"`
+	OpenAPI     OpenAPI     `yaml:"openapi" toml:"openapi"`
+	Auth        Auth        `yaml:"auth" toml:"auth"`
+	Idempotency Idempotency `yaml:"idempotency" toml:"idempotency"`
+	LogLevel    string      `yaml:"log_level" toml:"log_level"`
+	Memory      bool        `yaml:"memory" toml:"memory"`
+	AutoMigrate bool        `yaml:"auto_migrate" toml:"auto_migrate"`
+	// LegacyJSON makes the v1 transaction endpoints use the PascalCase
+	// members (CustomerID, CreatedAt, ...) they spoke before the API moved to
+	// snake_case, unless a request asks otherwise with the X-API-JSON header.
+	LegacyJSON bool `yaml:"legacy_json" toml:"legacy_json"`
+	// V1Sunset is the date (YYYY-MM-DD) after which the deprecated /v1 routes
+	// may be removed, announced in their Sunset header. Empty omits the header.
+	V1Sunset string `yaml:"v1_sunset" toml:"v1_sunset"`
+}
+
+type HTTP struct {
+	Addr         string   `yaml:"addr" toml:"addr"`
+	ReadTimeout  Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+}
+
+type DB struct {
+	Host            string   `yaml:"host" toml:"host"`
+	Port            int      `yaml:"port" toml:"port"`
+	User            string   `yaml:"user" toml:"user"`
+	Password        string   `yaml:"password" toml:"password"`
+	PasswordFile    string   `yaml:"password_file" toml:"password_file"`
+	Name            string   `yaml:"name" toml:"name"`
+	SSLMode         string   `yaml:"sslmode" toml:"sslmode"`
+	ConnectTimeout  Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+	MaxOpenConns    int      `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int      `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+}
+
+// Query bounds the database work done for one request. RouteTimeouts
+// overrides Timeout for single routes, keyed by method and route pattern as
+// in "GET /v1/transaction/filter". A zero timeout disables the deadline.
+type Query struct {
+	Timeout       Duration            `yaml:"timeout" toml:"timeout"`
+	RouteTimeouts map[string]Duration `yaml:"route_timeouts" toml:"route_timeouts"`
+	SlowThreshold Duration            `yaml:"slow_threshold" toml:"slow_threshold"`
+}
+
+// OpenAPI controls checking traffic against the spec served under /docs.
+// Rejected requests get 400 or 422; response violations are only logged.
+type OpenAPI struct {
+	ValidateRequests  bool `yaml:"validate_requests" toml:"validate_requests"`
+	ValidateResponses bool `yaml:"validate_responses" toml:"validate_responses"`
+}
+
+// Auth controls authentication. When enabled, every /v1 and /v2 request
+// needs an API key in X-API-Key or a JWT in "Authorization: Bearer"; /docs
+// stays public. When disabled, all callers share one set of idempotency
+// keys.
+type Auth struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	JWT     JWT  `yaml:"jwt" toml:"jwt"`
+}
+
+// JWT configures bearer tokens. HS256 tokens are checked with Secret and
+// RS256 tokens with PublicKey, a PEM encoded RSA key; with neither, bearer
+// tokens are rejected. Issuer and Audience, when set, must match the iss and
+// aud claims. The roles claim lists the roles of the caller. Like the
+// database password, both keys may be read from files.
+type JWT struct {
+	Issuer        string `yaml:"issuer" toml:"issuer"`
+	Audience      string `yaml:"audience" toml:"audience"`
+	Secret        string `yaml:"secret" toml:"secret"`
+	SecretFile    string `yaml:"secret_file" toml:"secret_file"`
+	PublicKey     string `yaml:"public_key" toml:"public_key"`
+	PublicKeyFile string `yaml:"public_key_file" toml:"public_key_file"`
+}
+
+// RSAPublicKey parses PublicKey, returning nil when it is empty.
+func (j JWT) RSAPublicKey() (*rsa.PublicKey, error) {
+	if j.PublicKey == "" {
+		return nil, nil
+	}
+	return jwt.ParseRSAPublicKeyFromPEM([]byte(j.PublicKey))
+}
+
+// Idempotency controls the Idempotency-Key header of create requests. The
+// response to a key is kept for TTL; expired keys are deleted every
+// CleanupInterval.
+type Idempotency struct {
+	TTL             Duration `yaml:"ttl" toml:"ttl"`
+	CleanupInterval Duration `yaml:"cleanup_interval" toml:"cleanup_interval"`
+}
+
+// Routes returns RouteTimeouts as plain durations.
+func (q Query) Routes() map[string]time.Duration {
+	routes := make(map[string]time.Duration, len(q.RouteTimeouts))
+	for route, d := range q.RouteTimeouts {
+		routes[route] = d.Duration()
+	}
+	return routes
+}
+
+// DSN returns the lib/pq connection string. Values are quoted so that
+// passwords containing spaces or quotes survive.
+func (db DB) DSN() string {
+	params := []struct{ key, value string }{
+		{"host", db.Host},
+		{"port", strconv.Itoa(db.Port)},
+		{"user", db.User},
+		{"password", db.Password},
+		{"dbname", db.Name},
+		{"sslmode", db.SSLMode},
+		{"connect_timeout", strconv.Itoa(int(db.ConnectTimeout.Duration().Seconds()))},
+	}
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(p.value)
+		parts = append(parts, fmt.Sprintf("%s='%s'", p.key, value))
+	}
+	return strings.Join(parts, " ")
+}
+
+func Default() Config {
+	return Config{
+		HTTP: HTTP{
+			Addr:         ":8080",
+			ReadTimeout:  Duration(15 * time.Second),
+			WriteTimeout: Duration(30 * time.Second),
+			IdleTimeout:  Duration(60 * time.Second),
+		},
+		DB: DB{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Name:            "postgres",
+			SSLMode:         "disable",
+			ConnectTimeout:  Duration(5 * time.Second),
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: Duration(30 * time.Minute),
+		},
+		Query: Query{
+			Timeout:       Duration(5 * time.Second),
+			SlowThreshold: Duration(500 * time.Millisecond),
+		},
+		OpenAPI: OpenAPI{
+			ValidateRequests: true,
+		},
+		Auth: Auth{
+			Enabled: true,
+		},
+		Idempotency: Idempotency{
+			TTL:             Duration(24 * time.Hour),
+			CleanupInterval: Duration(time.Hour),
+		},
+		LogLevel:   "info",
+		LegacyJSON: true,
+		V1Sunset:   "2027-04-30",
+	}
+}
+
+// Load registers the configuration flags on fs, parses args and resolves the
+// configuration from defaults, the config file, the environment and the
+// flags, then validates it. Positional arguments are left in fs.Args().
+func Load(fs *flag.FlagSet, args []string) (Config, error) {
+	cfg := Default()
+	configFile := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "path to a YAML or TOML config file (env "+envPrefix+"CONFIG)")
+	options := cfg.options()
+	for _, o := range options {
+		fs.Var(o, o.flagName(), fmt.Sprintf("%s (env %s)", o.usage, o.envName()))
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return cfg, err
+		}
+	}
+	for _, o := range options {
+		if value, ok := os.LookupEnv(o.envName()); ok {
+			if err := o.set(value); err != nil {
+				return cfg, fmt.Errorf("%s: %w", o.envName(), err)
+			}
+		}
+	}
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		if o, ok := f.Value.(*option); ok && flagErr == nil {
+			if err := o.set(o.flagValue); err != nil {
+				flagErr = fmt.Errorf("-%s: %w", f.Name, err)
+			}
+		}
+	})
+	if flagErr != nil {
+		return cfg, flagErr
+	}
+
+	if cfg.DB.PasswordFile != "" {
+		b, err := os.ReadFile(cfg.DB.PasswordFile)
+		if err != nil {
+			return cfg, fmt.Errorf("db.password_file: %w", err)
+		}
+		cfg.DB.Password = strings.TrimRight(string(b), "\r\n")
+	}
+	if cfg.Auth.JWT.SecretFile != "" {
+		b, err := os.ReadFile(cfg.Auth.JWT.SecretFile)
+		if err != nil {
+			return cfg, fmt.Errorf("auth.jwt.secret_file: %w", err)
+		}
+		cfg.Auth.JWT.Secret = strings.TrimRight(string(b), "\r\n")
+	}
+	if cfg.Auth.JWT.PublicKeyFile != "" {
+		b, err := os.ReadFile(cfg.Auth.JWT.PublicKeyFile)
+		if err != nil {
+			return cfg, fmt.Errorf("auth.jwt.public_key_file: %w", err)
+		}
+		cfg.Auth.JWT.PublicKey = string(b)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(b, c)
+	} else {
+		err = yaml.Unmarshal(b, c)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if _, port, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+		fail("http.addr", "must be host:port, got %q", c.HTTP.Addr)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		fail("http.addr", "invalid port %q", port)
+	}
+	for key, d := range map[string]Duration{
+		"http.read_timeout":            c.HTTP.ReadTimeout,
+		"http.write_timeout":           c.HTTP.WriteTimeout,
+		"http.idle_timeout":            c.HTTP.IdleTimeout,
+		"idempotency.ttl":              c.Idempotency.TTL,
+		"idempotency.cleanup_interval": c.Idempotency.CleanupInterval,
+	} {
+		if d <= 0 {
+			fail(key, "must be positive")
+		}
+	}
+	if c.Query.Timeout < 0 {
+		fail("query.timeout", "must not be negative")
+	}
+	if c.Query.SlowThreshold < 0 {
+		fail("query.slow_threshold", "must not be negative")
+	}
+	for route, d := range c.Query.RouteTimeouts {
+		if method, path, ok := strings.Cut(route, " "); !ok || method == "" || !strings.HasPrefix(path, "/") {
+			fail("query.route_timeouts", "route %q must be a method and a path, e.g. \"GET /v1/transactions\"", route)
+		}
+		if d < 0 {
+			fail("query.route_timeouts", "timeout of %q must not be negative", route)
+		}
+	}
+	if _, err := c.Level(); err != nil {
+		fail("log_level", "%v", err)
+	}
+	if _, err := c.Sunset(); err != nil {
+		fail("v1_sunset", "must be a date like 2027-04-30, got %q", c.V1Sunset)
+	}
+	if c.Auth.JWT.Secret != "" && len(c.Auth.JWT.Secret) < 32 {
+		fail("auth.jwt.secret", "must be at least 32 bytes for HS256")
+	}
+	if _, err := c.Auth.JWT.RSAPublicKey(); err != nil {
+		fail("auth.jwt.public_key", "%v", err)
+	}
+
+	if c.Memory {
+		return errors.Join(errs...)
+	}
+
+	if c.DB.Host == "" {
+		fail("db.host", "is required")
+	}
+	if c.DB.Port < 1 || c.DB.Port > 65535 {
+		fail("db.port", "must be between 1 and 65535")
+	}
+	if c.DB.User == "" {
+		fail("db.user", "is required")
+	}
+	if c.DB.Name == "" {
+		fail("db.name", "is required")
+	}
+	switch c.DB.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		fail("db.sslmode", "unsupported mode %q", c.DB.SSLMode)
+	}
+	if c.DB.ConnectTimeout < 0 {
+		fail("db.connect_timeout", "must not be negative")
+	}
+	if c.DB.MaxOpenConns < 0 {
+		fail("db.max_open_conns", "must not be negative")
+	}
+	if c.DB.MaxIdleConns < 0 {
+		fail("db.max_idle_conns", "must not be negative")
+	}
+	if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		fail("db.max_idle_conns", "must not exceed db.max_open_conns")
+	}
+	if c.DB.ConnMaxLifetime < 0 {
+		fail("db.conn_max_lifetime", "must not be negative")
+	}
+	return errors.Join(errs...)
+}
+
+// Level returns the slog level named by LogLevel.
+func (c Config) Level() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.LogLevel))
+	return level, err
+}
+
+// Sunset returns the date in V1Sunset, or the zero time when it is empty.
+func (c Config) Sunset() (time.Time, error) {
+	if c.V1Sunset == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", c.V1Sunset)
+}
+
+// Redacted returns a copy that is safe to log.
+func (c Config) Redacted() Config {
+	if c.DB.Password != "" {
+		c.DB.Password = "[REDACTED]"
+	}
+	if c.Auth.JWT.Secret != "" {
+		c.Auth.JWT.Secret = "[REDACTED]"
+	}
+	return c
+}
+
+// String renders the configuration as YAML with secrets redacted.
+func (c Config) String() string {
+	b, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration written as a string such as "30s" in config
+// files.
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.UnmarshalText([]byte(node.Value))
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// option binds one configuration value to its environment variable and
+// command line flag. It implements flag.Value but only records the flag's
+// value, which Load applies after the file and the environment.
+type option struct {
+	key       string
+	usage     string
+	set       func(string) error
+	get       func() string
+	isBool    bool
+	flagValue string
+}
+
+func (o *option) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(o.key)
+}
+
+func (o *option) envName() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(o.key, ".", "_"))
+}
+
+func (o *option) String() string {
+	if o == nil || o.get == nil {
+		return ""
+	}
+	return o.get()
+}
+
+func (o *option) Set(value string) error {
+	if err := o.set(value); err != nil {
+		return err
+	}
+	o.flagValue = value
+	return nil
+}
+
+// IsBoolFlag lets boolean options be passed as plain -flag.
+func (o *option) IsBoolFlag() bool {
+	return o.isBool
+}
+
+func stringOption(key, usage string, p *string) *option {
+	return &option{
+		key:   key,
+		usage: usage,
+		set:   func(v string) error { *p = v; return nil },
+		get:   func() string { return *p },
+	}
+}
+
+func intOption(key, usage string, p *int) *option {
+	return &option{
+		key:   key,
+		usage: usage,
+		set: func(v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid integer %q", v)
+			}
+			*p = n
+			return nil
+		},
+		get: func() string { return strconv.Itoa(*p) },
+	}
+}
+
+func boolOption(key, usage string, p *bool) *option {
+	return &option{
+		key:   key,
+		usage: usage,
+		set: func(v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid boolean %q", v)
+			}
+			*p = b
+			return nil
+		},
+		get:    func() string { return strconv.FormatBool(*p) },
+		isBool: true,
+	}
+}
+
+func durationOption(key, usage string, p *Duration) *option {
+	return &option{
+		key:   key,
+		usage: usage,
+		set:   func(v string) error { return p.UnmarshalText([]byte(v)) },
+		get:   func() string { return p.String() },
+	}
+}
+
+// durationMapOption reads comma separated key=duration pairs such as
+// "GET /v1/transactions=10s,GET /v1/transaction/filter=15s". A value from the
+// environment or a flag replaces the whole map.
+func durationMapOption(key, usage string, p *map[string]Duration) *option {
+	return &option{
+		key:   key,
+		usage: usage,
+		set: func(v string) error {
+			m := make(map[string]Duration)
+			for _, pair := range strings.Split(v, ",") {
+				if strings.TrimSpace(pair) == "" {
+					continue
+				}
+				k, value, ok := strings.Cut(pair, "=")
+				if !ok {
+					return fmt.Errorf("invalid pair %q, want key=duration", pair)
+				}
+				var d Duration
+				if err := d.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+					return err
+				}
+				m[strings.TrimSpace(k)] = d
+			}
+			*p = m
+			return nil
+		},
+		get: func() string {
+			pairs := make([]string, 0, len(*p))
+			for k, d := range *p {
+				pairs = append(pairs, k+"="+d.String())
+			}
+			sort.Strings(pairs)
+			return strings.Join(pairs, ",")
+		},
+	}
+}
+
+// options lists every setting that can come from the environment or flags.
+// The keys match the config file layout.
+func (c *Config) options() []*option {
+	return []*option{
+		stringOption("http.addr", "HTTP listen address", &c.HTTP.Addr),
+		durationOption("http.read_timeout", "maximum duration for reading a request", &c.HTTP.ReadTimeout),
+		durationOption("http.write_timeout", "maximum duration for writing a response", &c.HTTP.WriteTimeout),
+		durationOption("http.idle_timeout", "keep-alive idle timeout", &c.HTTP.IdleTimeout),
+		stringOption("db.host", "PostgreSQL host", &c.DB.Host),
+		intOption("db.port", "PostgreSQL port", &c.DB.Port),
+		stringOption("db.user", "PostgreSQL user", &c.DB.User),
+		stringOption("db.password", "PostgreSQL password", &c.DB.Password),
+		stringOption("db.password_file", "file containing the PostgreSQL password", &c.DB.PasswordFile),
+		stringOption("db.name", "PostgreSQL database name", &c.DB.Name),
+		stringOption("db.sslmode", "PostgreSQL sslmode", &c.DB.SSLMode),
+		durationOption("db.connect_timeout", "PostgreSQL connect timeout", &c.DB.ConnectTimeout),
+		intOption("db.max_open_conns", "maximum open database connections (0 is unlimited)", &c.DB.MaxOpenConns),
+		intOption("db.max_idle_conns", "maximum idle database connections", &c.DB.MaxIdleConns),
+		durationOption("db.conn_max_lifetime", "maximum lifetime of a database connection (0 is unlimited)", &c.DB.ConnMaxLifetime),
+		durationOption("query.timeout", "default deadline for the database work of a request (0 disables it)", &c.Query.Timeout),
+		durationMapOption("query.route_timeouts", "per-route deadlines as \"METHOD /path=duration\" pairs separated by commas", &c.Query.RouteTimeouts),
+		durationOption("query.slow_threshold", "log statements slower than this (0 disables the log)", &c.Query.SlowThreshold),
+		boolOption("openapi.validate_requests", "reject requests that do not match the OpenAPI spec", &c.OpenAPI.ValidateRequests),
+		boolOption("openapi.validate_responses", "log responses that do not match the OpenAPI spec (for development)", &c.OpenAPI.ValidateResponses),
+		boolOption("auth.enabled", "require an API key or a JWT on every API request", &c.Auth.Enabled),
+		stringOption("auth.jwt.issuer", "required iss claim of bearer tokens (empty skips the check)", &c.Auth.JWT.Issuer),
+		stringOption("auth.jwt.audience", "required aud claim of bearer tokens (empty skips the check)", &c.Auth.JWT.Audience),
+		stringOption("auth.jwt.secret", "HS256 secret of bearer tokens", &c.Auth.JWT.Secret),
+		stringOption("auth.jwt.secret_file", "file containing the HS256 secret of bearer tokens", &c.Auth.JWT.SecretFile),
+		stringOption("auth.jwt.public_key", "PEM encoded RSA public key of RS256 bearer tokens", &c.Auth.JWT.PublicKey),
+		stringOption("auth.jwt.public_key_file", "file containing the PEM encoded RSA public key of RS256 bearer tokens", &c.Auth.JWT.PublicKeyFile),
+		durationOption("idempotency.ttl", "how long the response to an Idempotency-Key is replayed", &c.Idempotency.TTL),
+		durationOption("idempotency.cleanup_interval", "how often expired idempotency keys are deleted", &c.Idempotency.CleanupInterval),
+		stringOption("log_level", "log level: debug, info, warn or error", &c.LogLevel),
+		boolOption("memory", "serve from an in-memory store instead of PostgreSQL, printing an admin API key when auth is enabled", &c.Memory),
+		boolOption("auto_migrate", "apply pending migrations before serving", &c.AutoMigrate),
+		boolOption("legacy_json", "v1 transaction endpoints default to the legacy PascalCase members", &c.LegacyJSON),
+		stringOption("v1_sunset", "date (YYYY-MM-DD) announced in the Sunset header of the deprecated /v1 routes; empty omits it", &c.V1Sunset),
+	}
+}
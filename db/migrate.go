@@ -0,0 +1,251 @@
+// Package db embeds the SQL schema migrations and applies them to PostgreSQL.
+//
+// The applied version is tracked in the schema_migrations table using the same
+// layout as the migrate CLI, so databases migrated with either tool are
+// recognized.
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// advisoryLockID serializes concurrent migrators on the same database.
+const advisoryLockID = 4_302_117
+
+var migrationName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+var ErrDirty = errors.New("database schema is dirty: a previous migration failed, fix it manually and force the version")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+// Migrations returns the embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		body, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s is missing its up or down script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// LatestVersion is the schema version the code expects.
+func LatestVersion() int {
+	migrations, err := Migrations()
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func (m *Migrator) ensureVersionTable() error {
+	_, err := m.db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	return err
+}
+
+// Version returns the applied schema version, 0 when nothing is applied.
+func (m *Migrator) Version() (int, error) {
+	if err := m.ensureVersionTable(); err != nil {
+		return 0, err
+	}
+	var version int
+	var dirty bool
+	err := m.db.QueryRow("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return version, ErrDirty
+	}
+	return version, nil
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = MigrationStatus{Migration: migration, Applied: migration.Version <= version}
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up() error {
+	return m.To(LatestVersion())
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	target := 0
+	for _, migration := range m.migrations {
+		if migration.Version < version {
+			target = migration.Version
+		}
+	}
+	return m.To(target)
+}
+
+// To migrates up or down until the schema is at the given version. Version 0
+// rolls back every migration.
+func (m *Migrator) To(target int) error {
+	if target != 0 && m.find(target) < 0 {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+
+	// Session-level advisory locks belong to one connection, so hold one
+	// aside for the lock while the migrations run on the pool.
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", advisoryLockID)
+
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if version != 0 && m.find(version) < 0 {
+		return fmt.Errorf("applied version %d has no embedded migration", version)
+	}
+
+	for version < target {
+		next := m.migrations[m.find(version)+1]
+		if err := m.apply(next.Up, next.Version); err != nil {
+			return fmt.Errorf("migration %d_%s up: %w", next.Version, next.Name, err)
+		}
+		version = next.Version
+	}
+	for version > target {
+		i := m.find(version)
+		previous := 0
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+		current := m.migrations[i]
+		if err := m.apply(current.Down, previous); err != nil {
+			return fmt.Errorf("migration %d_%s down: %w", current.Version, current.Name, err)
+		}
+		version = previous
+	}
+	return nil
+}
+
+// apply runs one script and records the resulting version in the same
+// transaction, so a failed script leaves the schema untouched.
+func (m *Migrator) apply(script string, version int) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", version); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// find returns the index of the migration with the given version, -1 when
+// there is none. Since version 0 is not a migration, it also yields -1, the
+// position before the first migration.
+func (m *Migrator) find(version int) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
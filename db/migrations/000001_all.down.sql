@@ -1,6 +1,7 @@
-drop table if EXISTS tbl-customer;
+DROP TABLE IF EXISTS TransactionViews;
 
-drop table if EXISTS tbl-items;
+DROP TABLE IF EXISTS tbl_transaction;
 
-drop table if EXISTS tbl-transaction;
+DROP TABLE IF EXISTS tbl_items;
 
+DROP TABLE IF EXISTS tbl_customer;
@@ -0,0 +1,17 @@
+DROP VIEW IF EXISTS TransactionViews;
+
+ALTER TABLE tbl_transaction DROP COLUMN IF EXISTS price;
+
+CREATE TABLE IF NOT EXISTS TransactionViews (
+                                  id INTEGER PRIMARY KEY,
+                                  customer_id INTEGER REFERENCES tbl_customer(id),
+                                  customer_name VARCHAR,
+                                  item_id INTEGER REFERENCES tbl_items(id),
+                                  item_name VARCHAR,
+                                  qty INTEGER,
+                                  price DECIMAL,
+                                  amount DECIMAL,
+                                  created_at TIMESTAMP,
+                                  updated_at TIMESTAMP,
+                                  deleted_at TIMESTAMP
+);
@@ -0,0 +1,22 @@
+DROP TABLE IF EXISTS TransactionViews;
+
+ALTER TABLE tbl_transaction ADD COLUMN IF NOT EXISTS price DECIMAL;
+
+UPDATE tbl_transaction SET price = amount / qty WHERE price IS NULL AND qty <> 0;
+
+CREATE OR REPLACE VIEW TransactionViews AS
+SELECT t.id,
+       t.customer_id,
+       c.customer_name,
+       t.item_id,
+       i.item_name,
+       t.qty,
+       t.price,
+       t.amount,
+       t.created_at,
+       COALESCE(t.updated_at, t.created_at) AS updated_at,
+       t.deleted_at
+FROM tbl_transaction t
+         INNER JOIN tbl_customer c ON t.customer_id = c.id
+         INNER JOIN tbl_items i ON t.item_id = i.id
+WHERE t.deleted_at IS NULL;
@@ -0,0 +1,26 @@
+DROP VIEW IF EXISTS TransactionViews;
+
+ALTER TABLE tbl_customer ALTER COLUMN balance TYPE DECIMAL;
+
+ALTER TABLE tbl_items ALTER COLUMN cost TYPE DECIMAL;
+ALTER TABLE tbl_items ALTER COLUMN price TYPE DECIMAL;
+
+ALTER TABLE tbl_transaction ALTER COLUMN price TYPE DECIMAL;
+ALTER TABLE tbl_transaction ALTER COLUMN amount TYPE DECIMAL;
+
+CREATE OR REPLACE VIEW TransactionViews AS
+SELECT t.id,
+       t.customer_id,
+       c.customer_name,
+       t.item_id,
+       i.item_name,
+       t.qty,
+       t.price,
+       t.amount,
+       t.created_at,
+       COALESCE(t.updated_at, t.created_at) AS updated_at,
+       t.deleted_at
+FROM tbl_transaction t
+         INNER JOIN tbl_customer c ON t.customer_id = c.id
+         INNER JOIN tbl_items i ON t.item_id = i.id
+WHERE t.deleted_at IS NULL;
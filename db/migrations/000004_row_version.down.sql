@@ -0,0 +1,3 @@
+ALTER TABLE tbl_transaction DROP COLUMN IF EXISTS version;
+ALTER TABLE tbl_items DROP COLUMN IF EXISTS version;
+ALTER TABLE tbl_customer DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+ALTER TABLE tbl_customer ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE tbl_items ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE tbl_transaction ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
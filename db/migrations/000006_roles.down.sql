@@ -0,0 +1,3 @@
+ALTER TABLE tbl_api_key DROP COLUMN IF EXISTS role;
+DROP TABLE IF EXISTS tbl_role_permission;
+DROP TABLE IF EXISTS tbl_role;
@@ -0,0 +1,44 @@
+CREATE TABLE tbl_role (
+    name VARCHAR PRIMARY KEY,
+    description VARCHAR NOT NULL DEFAULT ''
+);
+
+CREATE TABLE tbl_role_permission (
+    role VARCHAR NOT NULL REFERENCES tbl_role(name) ON DELETE CASCADE,
+    permission VARCHAR NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO tbl_role (name, description) VALUES
+    ('cashier', 'Sells to customers and reads the catalog'),
+    ('manager', 'Runs a shop: edits customers, items and prices and voids transactions'),
+    ('admin', 'Deletes customers and items and manages API keys');
+
+INSERT INTO tbl_role_permission (role, permission) VALUES
+    ('cashier', 'customers:read'),
+    ('cashier', 'items:read'),
+    ('cashier', 'transactions:read'),
+    ('cashier', 'transactions:create'),
+    ('manager', 'customers:read'),
+    ('manager', 'customers:write'),
+    ('manager', 'items:read'),
+    ('manager', 'items:write'),
+    ('manager', 'transactions:read'),
+    ('manager', 'transactions:create'),
+    ('manager', 'transactions:write'),
+    ('manager', 'transactions:void'),
+    ('admin', 'customers:read'),
+    ('admin', 'customers:write'),
+    ('admin', 'customers:delete'),
+    ('admin', 'items:read'),
+    ('admin', 'items:write'),
+    ('admin', 'items:delete'),
+    ('admin', 'transactions:read'),
+    ('admin', 'transactions:create'),
+    ('admin', 'transactions:write'),
+    ('admin', 'transactions:void'),
+    ('admin', 'api_keys:manage');
+
+-- Keys created before roles existed could do everything.
+ALTER TABLE tbl_api_key ADD COLUMN role VARCHAR NOT NULL DEFAULT 'admin' REFERENCES tbl_role(name);
+ALTER TABLE tbl_api_key ALTER COLUMN role DROP DEFAULT;
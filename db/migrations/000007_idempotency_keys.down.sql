@@ -0,0 +1 @@
+DROP TABLE IF EXISTS tbl_idempotency_key;
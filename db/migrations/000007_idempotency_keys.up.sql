@@ -0,0 +1,13 @@
+CREATE TABLE tbl_idempotency_key (
+    principal VARCHAR NOT NULL,
+    idempotency_key VARCHAR NOT NULL,
+    request_hash BYTEA NOT NULL,
+    status INTEGER,
+    headers JSONB,
+    body BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (principal, idempotency_key)
+);
+
+CREATE INDEX tbl_idempotency_key_expires_at_idx ON tbl_idempotency_key (expires_at);
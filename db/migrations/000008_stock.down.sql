@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tbl_stock_movements;
+ALTER TABLE tbl_items DROP COLUMN IF EXISTS allow_backorder;
+ALTER TABLE tbl_items DROP COLUMN IF EXISTS stock;
@@ -0,0 +1,18 @@
+ALTER TABLE tbl_items ADD COLUMN stock INTEGER NOT NULL DEFAULT 0;
+-- Items sold before stock was tracked keep selling until they are restocked
+-- or their allow_backorder is turned off; new items refuse to go negative.
+ALTER TABLE tbl_items ADD COLUMN allow_backorder BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE tbl_items ALTER COLUMN allow_backorder SET DEFAULT false;
+
+CREATE TABLE tbl_stock_movements (
+    id SERIAL PRIMARY KEY,
+    item_id INTEGER NOT NULL REFERENCES tbl_items(id),
+    kind VARCHAR NOT NULL CHECK (kind IN ('sale', 'restock', 'adjustment', 'return')),
+    qty INTEGER NOT NULL CHECK (qty <> 0),
+    stock_after INTEGER NOT NULL,
+    transaction_id INTEGER REFERENCES tbl_transaction(id),
+    reason VARCHAR NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX tbl_stock_movements_item_id_idx ON tbl_stock_movements (item_id, id);
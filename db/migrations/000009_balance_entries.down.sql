@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tbl_balance_entries;
+ALTER TABLE tbl_customer ALTER COLUMN balance DROP NOT NULL;
+ALTER TABLE tbl_customer ALTER COLUMN balance DROP DEFAULT;
@@ -0,0 +1,24 @@
+UPDATE tbl_customer SET balance = 0 WHERE balance IS NULL;
+ALTER TABLE tbl_customer ALTER COLUMN balance SET DEFAULT 0;
+ALTER TABLE tbl_customer ALTER COLUMN balance SET NOT NULL;
+
+CREATE TABLE tbl_balance_entries (
+    id SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES tbl_customer(id),
+    kind VARCHAR NOT NULL CHECK (kind IN ('topup', 'purchase', 'refund', 'adjustment')),
+    amount NUMERIC(14, 2) NOT NULL CHECK (amount <> 0),
+    balance_after NUMERIC(14, 2) NOT NULL,
+    transaction_id INTEGER REFERENCES tbl_transaction(id),
+    reason VARCHAR NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX tbl_balance_entries_customer_id_idx ON tbl_balance_entries (customer_id, created_at, id);
+
+-- Balances set before the ledger existed have no history; one adjustment per
+-- customer makes the ledger add up to the stored balance.
+INSERT INTO tbl_balance_entries (customer_id, kind, amount, balance_after, reason)
+SELECT id, 'adjustment', balance, balance, 'balance before the ledger was introduced'
+FROM tbl_customer
+WHERE balance <> 0
+ORDER BY id;
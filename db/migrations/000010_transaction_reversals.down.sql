@@ -0,0 +1,24 @@
+DROP VIEW IF EXISTS TransactionViews;
+
+-- Voids and refunds stay as transactions with a negative qty and amount, so
+-- that balances and stock still add up.
+ALTER TABLE tbl_transaction DROP COLUMN IF EXISTS original_transaction_id;
+ALTER TABLE tbl_transaction DROP COLUMN IF EXISTS reason;
+ALTER TABLE tbl_transaction DROP COLUMN IF EXISTS type;
+
+CREATE VIEW TransactionViews AS
+SELECT t.id,
+       t.customer_id,
+       c.customer_name,
+       t.item_id,
+       i.item_name,
+       t.qty,
+       t.price,
+       t.amount,
+       t.created_at,
+       COALESCE(t.updated_at, t.created_at) AS updated_at,
+       t.deleted_at
+FROM tbl_transaction t
+         INNER JOIN tbl_customer c ON t.customer_id = c.id
+         INNER JOIN tbl_items i ON t.item_id = i.id
+WHERE t.deleted_at IS NULL;
@@ -0,0 +1,29 @@
+-- Voids and refunds are transactions of their own that point at the sale
+-- they reverse and carry a negative qty and amount.
+ALTER TABLE tbl_transaction ADD COLUMN type VARCHAR NOT NULL DEFAULT 'sale' CHECK (type IN ('sale', 'void', 'refund'));
+ALTER TABLE tbl_transaction ADD COLUMN original_transaction_id INTEGER REFERENCES tbl_transaction(id);
+ALTER TABLE tbl_transaction ADD COLUMN reason VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE tbl_transaction ADD CONSTRAINT tbl_transaction_original_check CHECK ((type = 'sale') = (original_transaction_id IS NULL));
+
+CREATE INDEX tbl_transaction_original_transaction_id_idx ON tbl_transaction (original_transaction_id);
+
+DROP VIEW IF EXISTS TransactionViews;
+
+CREATE VIEW TransactionViews AS
+SELECT t.id,
+       t.customer_id,
+       c.customer_name,
+       t.item_id,
+       i.item_name,
+       t.qty,
+       t.price,
+       t.amount,
+       t.created_at,
+       COALESCE(t.updated_at, t.created_at) AS updated_at,
+       t.deleted_at,
+       t.type,
+       t.original_transaction_id
+FROM tbl_transaction t
+         INNER JOIN tbl_customer c ON t.customer_id = c.id
+         INNER JOIN tbl_items i ON t.item_id = i.id
+WHERE t.deleted_at IS NULL;
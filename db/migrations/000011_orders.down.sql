@@ -0,0 +1,4 @@
+-- The lines of orders stay as sales of their own.
+ALTER TABLE tbl_transaction DROP CONSTRAINT IF EXISTS tbl_transaction_order_check;
+ALTER TABLE tbl_transaction DROP COLUMN IF EXISTS order_id;
+DROP TABLE IF EXISTS tbl_orders;
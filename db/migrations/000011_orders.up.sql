@@ -0,0 +1,30 @@
+-- An order is the receipt of one purchase. Its lines are the sales in
+-- tbl_transaction that point at it; voids and refunds point at their sale
+-- instead.
+CREATE TABLE tbl_orders (
+    id SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES tbl_customer(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX tbl_orders_customer_id_idx ON tbl_orders (customer_id, id);
+
+ALTER TABLE tbl_transaction ADD COLUMN order_id INTEGER REFERENCES tbl_orders(id);
+
+-- Every sale so far becomes a one-line order with the id of the sale. Sales
+-- recorded without a customer cannot have an order and keep order_id NULL.
+INSERT INTO tbl_orders (id, customer_id, created_at)
+SELECT id, customer_id, COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM tbl_transaction
+WHERE type = 'sale' AND customer_id IS NOT NULL
+ORDER BY id;
+
+UPDATE tbl_transaction SET order_id = id WHERE type = 'sale' AND customer_id IS NOT NULL;
+
+SELECT setval(pg_get_serial_sequence('tbl_orders', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM tbl_orders;
+
+-- Only sales are lines of an order. New sales always are, but those left
+-- without an order above are not, so the constraint cannot require it.
+ALTER TABLE tbl_transaction ADD CONSTRAINT tbl_transaction_order_check CHECK (type = 'sale' OR order_id IS NULL);
+
+CREATE INDEX tbl_transaction_order_id_idx ON tbl_transaction (order_id);
@@ -0,0 +1,17 @@
+ALTER TABLE tbl_transaction DROP CONSTRAINT IF EXISTS tbl_transaction_order_check;
+ALTER TABLE tbl_transaction ADD CONSTRAINT tbl_transaction_order_check CHECK (type = 'sale' OR order_id IS NULL);
+
+ALTER TABLE tbl_transaction
+    ALTER COLUMN customer_id DROP NOT NULL,
+    ALTER COLUMN item_id DROP NOT NULL,
+    ALTER COLUMN qty DROP NOT NULL,
+    ALTER COLUMN created_at DROP NOT NULL;
+
+INSERT INTO tbl_orders (id, customer_id, created_at)
+SELECT order_id, customer_id, COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM tbl_transaction_incomplete
+WHERE order_id IS NOT NULL;
+
+INSERT INTO tbl_transaction SELECT * FROM tbl_transaction_incomplete;
+
+DROP TABLE IF EXISTS tbl_transaction_incomplete;
@@ -0,0 +1,24 @@
+-- Transactions recorded before their customer, item, quantity and creation
+-- time were required may lack one of them. No such row can be read as a
+-- transaction, so none was ever changed, voided or refunded. They move to
+-- tbl_transaction_incomplete with their ids, the one-line orders 000011 gave
+-- some of them are dropped and the columns become NOT NULL.
+CREATE TABLE tbl_transaction_incomplete (LIKE tbl_transaction);
+
+INSERT INTO tbl_transaction_incomplete
+SELECT *
+FROM tbl_transaction
+WHERE customer_id IS NULL OR item_id IS NULL OR qty IS NULL OR created_at IS NULL;
+
+DELETE FROM tbl_transaction WHERE id IN (SELECT id FROM tbl_transaction_incomplete);
+DELETE FROM tbl_orders WHERE id IN (SELECT order_id FROM tbl_transaction_incomplete);
+
+ALTER TABLE tbl_transaction
+    ALTER COLUMN customer_id SET NOT NULL,
+    ALTER COLUMN item_id SET NOT NULL,
+    ALTER COLUMN qty SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL;
+
+-- Every sale left has a customer and so an order.
+ALTER TABLE tbl_transaction DROP CONSTRAINT tbl_transaction_order_check;
+ALTER TABLE tbl_transaction ADD CONSTRAINT tbl_transaction_order_check CHECK ((type = 'sale') = (order_id IS NOT NULL));
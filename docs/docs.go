@@ -17,6 +17,14 @@ const docTemplate = `{
     "paths": {
         "/v1/customer/create": {
             "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Creates a new customer in the database",
                 "consumes": [
                     "application/json"
@@ -28,6 +36,7 @@ const docTemplate = `{
                     "customers"
                 ],
                 "summary": "Create a new customer",
+                "deprecated": true,
                 "parameters": [
                     {
                         "description": "Customer information",
@@ -35,15 +44,27 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/storage.Customer"
+                            "$ref": "#/definitions/handlers_v1.CustomerRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created customer",
                         "schema": {
-                            "$ref": "#/definitions/storage.Customer"
+                            "$ref": "#/definitions/handlers_v1.CustomerResponse"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            }
                         }
                     },
                     "400": {
@@ -52,22 +73,61 @@ const docTemplate = `{
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
                     }
                 }
             }
         },
         "/v1/customer/delete/{id}": {
             "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Soft deletes a customer from the database",
                 "tags": [
                     "customers"
                 ],
                 "summary": "Delete a customer",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "integer",
@@ -79,9 +139,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Customer deleted successfully",
+                        "description": "Deleted customer",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers_v1.CustomerResponse"
                         }
                     },
                     "400": {
@@ -90,28 +150,58 @@ const docTemplate = `{
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:delete",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
                     }
                 }
             }
         },
-        "/v1/customer/update/{id}": {
-            "put": {
-                "description": "Updates an existing customer in the database",
-                "consumes": [
-                    "application/json"
+        "/v1/customer/get/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Retrieves a single customer by its ID from the database",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "customers"
                 ],
-                "summary": "Update an existing customer",
+                "summary": "Get a single customer",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "integer",
@@ -121,24 +211,45 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Customer information",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/storage.Customer"
-                        }
+                        "type": "boolean",
+                        "description": "Return the customer even if it is soft-deleted",
+                        "name": "include_deleted",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Updated customer",
+                        "description": "Customer details",
                         "schema": {
-                            "$ref": "#/definitions/storage.Customer"
+                            "$ref": "#/definitions/handlers_v1.CustomerResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the customer, for If-Match"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid customer data",
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
@@ -148,20 +259,35 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
                     }
                 }
             }
         },
-        "/v1/customer/{id}": {
-            "get": {
-                "description": "Retrieves a single customer by its ID from the database",
+        "/v1/customer/restore/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Undoes a soft delete of a customer",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "customers"
                 ],
-                "summary": "Get a single customer",
+                "summary": "Restore a deleted customer",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "integer",
@@ -173,9 +299,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Customer details",
+                        "description": "Restored customer",
                         "schema": {
-                            "$ref": "#/definitions/storage.Customer"
+                            "$ref": "#/definitions/handlers_v1.CustomerResponse"
                         }
                     },
                     "400": {
@@ -184,39 +310,28 @@ const docTemplate = `{
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
-                    "404": {
-                        "description": "Customer not found",
+                    "401": {
+                        "description": "Missing or invalid credentials",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "403": {
+                        "description": "Missing permission customers:delete",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
-                    }
-                }
-            }
-        },
-        "/v1/customers": {
-            "get": {
-                "description": "Retrieves all customers from the database",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "customers"
-                ],
-                "summary": "Get all customers",
-                "responses": {
-                    "200": {
-                        "description": "List of customers",
+                    },
+                    "404": {
+                        "description": "Customer not found",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/storage.Customer"
-                            }
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Customer is not deleted",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
                     "500": {
@@ -224,13 +339,27 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
                     }
                 }
             }
         },
-        "/v1/item/create": {
-            "post": {
-                "description": "Creates a new item in the database",
+        "/v1/customer/update/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces an existing customer. The ID is taken from the path; an ID in the body is ignored. The balance is read-only; it changes through top-ups and adjustments",
                 "consumes": [
                     "application/json"
                 ],
@@ -238,67 +367,85 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "items"
+                    "customers"
                 ],
-                "summary": "Create a new item",
+                "summary": "Update an existing customer",
+                "deprecated": true,
                 "parameters": [
                     {
-                        "description": "Item information",
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the customer as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Customer information",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/storage.Item"
+                            "$ref": "#/definitions/handlers_v1.CustomerUpdateRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created item",
+                    "200": {
+                        "description": "Updated customer",
                         "schema": {
-                            "$ref": "#/definitions/storage.Item"
+                            "$ref": "#/definitions/handlers_v1.CustomerResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated customer"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid item data",
+                        "description": "Invalid customer data",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "401": {
+                        "description": "Missing or invalid credentials",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
-                    }
-                }
-            }
-        },
-        "/v1/item/delete/{id}": {
-            "delete": {
-                "description": "Soft deletes an item from the database",
-                "tags": [
-                    "items"
-                ],
-                "summary": "Delete an item",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Item ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Item deleted successfully",
+                    },
+                    "403": {
+                        "description": "Missing permission customers:write",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
-                    "400": {
-                        "description": "Invalid item ID",
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Customer was modified since the version in the body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Customer was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
@@ -308,97 +455,112 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
                     }
                 }
-            }
-        },
-        "/v1/item/update/{id}": {
-            "put": {
-                "description": "Updates an existing item in the database",
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies an RFC 7396 JSON merge patch. Only customer_name can be changed; members that are absent keep their value, and balance is read-only. Returns the updated customer as stored",
                 "consumes": [
+                    "application/merge-patch+json",
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "items"
+                    "customers"
                 ],
-                "summary": "Update an existing item",
+                "summary": "Partially update a customer",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Item ID",
+                        "description": "Customer ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Item information",
-                        "name": "input",
+                        "type": "string",
+                        "description": "ETag of the customer as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Members to change",
+                        "name": "patch",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/storage.Item"
+                            "$ref": "#/definitions/handlers_v1.CustomerUpdateRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Updated item",
+                        "description": "Updated customer",
                         "schema": {
-                            "$ref": "#/definitions/storage.Item"
+                            "$ref": "#/definitions/handlers_v1.CustomerResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated customer"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid item data",
+                        "description": "Invalid customer ID or patch",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "401": {
+                        "description": "Missing or invalid credentials",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
-                    }
-                }
-            }
-        },
-        "/v1/item/{id}": {
-            "get": {
-                "description": "Retrieves a single item by its ID from the database",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "items"
-                ],
-                "summary": "Get a single item",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Item ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Item details",
+                    },
+                    "403": {
+                        "description": "Missing permission customers:write",
                         "schema": {
-                            "$ref": "#/definitions/storage.Item"
+                            "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
-                    "400": {
-                        "description": "Invalid item ID",
+                    "404": {
+                        "description": "Customer not found",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
-                    "404": {
-                        "description": "Item not found",
+                    "409": {
+                        "description": "Customer was modified since the version in the patch",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Customer was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
@@ -408,28 +570,84 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
                     }
                 }
             }
         },
-        "/v1/items": {
+        "/v1/customers": {
             "get": {
-                "description": "Retrieves all items from the database",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves all customers from the database",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "items"
+                    "customers"
+                ],
+                "summary": "Get all customers",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return every row as a plain array instead of a page (deprecated)",
+                        "name": "all",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted customers",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get all items",
                 "responses": {
                     "200": {
-                        "description": "List of items",
+                        "description": "List of customers",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/storage.Item"
-                            }
+                            "$ref": "#/definitions/lesson_storage.Page-handlers_v1_CustomerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
                     "500": {
@@ -437,13 +655,27 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
                     }
                 }
             }
         },
-        "/v1/transaction/create": {
+        "/v1/item/create": {
             "post": {
-                "description": "Creates a new transaction",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new item in the database",
                 "consumes": [
                     "application/json"
                 ],
@@ -451,35 +683,66 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "items"
                 ],
-                "summary": "Create a new transaction",
+                "summary": "Create a new item",
+                "deprecated": true,
                 "parameters": [
                     {
-                        "description": "Transaction information",
-                        "name": "user",
+                        "description": "Item information",
+                        "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/storage.Transaction"
+                            "$ref": "#/definitions/handlers_v1.ItemRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Created transaction",
+                    "201": {
+                        "description": "Created item",
                         "schema": {
-                            "$ref": "#/definitions/storage.Transaction"
+                            "$ref": "#/definitions/handlers_v1.ItemResponse"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid transaction data",
+                        "description": "Invalid item data",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized",
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
@@ -489,21 +752,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
                     }
                 }
             }
         },
-        "/v1/transaction/delete/{id}": {
+        "/v1/item/delete/{id}": {
             "delete": {
-                "description": "Soft deletes a transaction from the database",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft deletes an item from the database",
                 "tags": [
-                    "transactions"
+                    "items"
                 ],
-                "summary": "Delete a transaction",
+                "summary": "Delete an item",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Transaction ID",
+                        "description": "Item ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -511,44 +789,33 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Transaction deleted successfully",
+                        "description": "Deleted item",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers_v1.ItemResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid transaction ID",
+                        "description": "Invalid item ID",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "401": {
+                        "description": "Missing or invalid credentials",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
-                    }
-                }
-            }
-        },
-        "/v1/transaction/details": {
-            "get": {
-                "description": "Retrieves transaction details with customer and item information using INNER JOIN",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "transactions"
-                ],
-                "summary": "Get transaction details with customer and item information",
-                "responses": {
-                    "200": {
-                        "description": "List of transactions with customer and item details",
+                    },
+                    "403": {
+                        "description": "Missing permission items:delete",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/storage.TransactionView"
-                            }
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
                     "500": {
@@ -556,62 +823,83 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
                     }
                 }
             }
         },
-        "/v1/transaction/update/{id}": {
-            "put": {
-                "description": "Updates an existing transaction in the database",
-                "consumes": [
-                    "application/json"
+        "/v1/item/get/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Retrieves a single item by its ID from the database",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "items"
                 ],
-                "summary": "Update an existing transaction",
+                "summary": "Get a single item",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Transaction ID",
+                        "description": "Item ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Transaction information",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/storage.Transaction"
-                        }
+                        "type": "boolean",
+                        "description": "Return the item even if it is soft-deleted",
+                        "name": "include_deleted",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Updated transaction",
+                        "description": "Item details",
                         "schema": {
-                            "$ref": "#/definitions/storage.Transaction"
+                            "$ref": "#/definitions/handlers_v1.ItemResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the item, for If-Match"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid transaction data",
+                        "description": "Invalid item ID",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized",
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:read",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
                     "404": {
-                        "description": "Transaction not found",
+                        "description": "Item not found",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
@@ -621,24 +909,39 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
                     }
                 }
             }
         },
-        "/v1/transaction/{id}": {
-            "get": {
-                "description": "Retrieves a single transaction by its ID from the database",
+        "/v1/item/restore/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Undoes a soft delete of an item",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "items"
                 ],
-                "summary": "Get a single transaction",
+                "summary": "Restore a deleted item",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Transaction ID",
+                        "description": "Item ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -646,19 +949,37 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Transaction details",
+                        "description": "Restored item",
                         "schema": {
-                            "$ref": "#/definitions/storage.Transaction"
+                            "$ref": "#/definitions/handlers_v1.ItemResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid transaction ID",
+                        "description": "Invalid item ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:delete",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
                     },
                     "404": {
-                        "description": "Transaction not found",
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Item is not deleted",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
@@ -668,180 +989,6238 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
                     }
                 }
             }
         },
-        "/v1/transactions": {
-            "get": {
-                "description": "Retrieves all transactions from the database",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "transactions"
+        "/v1/item/update/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces an existing item. The ID is taken from the path; an ID in the body is ignored",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "items"
+                ],
+                "summary": "Update an existing item",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the item as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Item information",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v1.ItemUpdateRequest"
+                        }
+                    }
                 ],
-                "summary": "Get all transactions",
                 "responses": {
                     "200": {
-                        "description": "List of transactions",
+                        "description": "Updated item",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/storage.Transaction"
+                            "$ref": "#/definitions/handlers_v1.ItemResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated item"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid item data",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Item was modified since the version in the body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Item was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies an RFC 7396 JSON merge patch. Only item_name, cost, price, sort, allow_backorder can be changed; members that are absent keep their value. Returns the updated item as stored",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "items"
+                ],
+                "summary": "Partially update a item",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the item as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Members to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v1.ItemUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated item",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v1.ItemResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated item"
                             }
                         }
                     },
+                    "400": {
+                        "description": "Invalid item ID or patch",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Item was modified since the version in the patch",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Item was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/items": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves all items from the database",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "items"
+                ],
+                "summary": "Get all items",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return every row as a plain array instead of a page (deprecated)",
+                        "name": "all",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted items",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of items",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-handlers_v1_ItemResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/storage.ResponseError"
                         }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/transaction/create": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Records a purchase as an order of one line: the amount is computed from the item's current price and debited from the customer's balance atomically. Use /v2/orders to buy several items at once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Create a new transaction",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "Transaction information",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v1.TransactionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created transaction",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v1.TransactionResponse"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction data",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:create",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed, unknown customer or item, or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/transaction/delete/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft deletes a transaction from the database. The customer's balance and the item's stock are not touched; void or refund a sale to reverse it",
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Delete a transaction",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transaction deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:void",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/transaction/details": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves transaction details with customer and item information using INNER JOIN",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction details with customer and item information",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return every row as a plain array instead of a page (deprecated)",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of transactions with customer and item details",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-handlers_v1_TransactionViewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/transaction/filter": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Filters transactions by ID, customer and item, creation date, amount and quantity ranges, with sorting",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Filter transactions",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Customer IDs, comma separated or repeated",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Item IDs, comma separated or repeated",
+                        "name": "item_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive partial customer name",
+                        "name": "customer_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive partial item name",
+                        "name": "item_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Inclusive lower bound on created_at (RFC 3339 or YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Exclusive upper bound on created_at (RFC 3339 or YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum amount",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum amount",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum quantity",
+                        "name": "min_qty",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum quantity",
+                        "name": "max_qty",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "customer_name",
+                            "item_name",
+                            "qty",
+                            "price",
+                            "amount",
+                            "created_at"
+                        ],
+                        "type": "string",
+                        "description": "Sort column",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return every row as a plain array instead of a page (deprecated)",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of filtered transactions",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-handlers_v1_TransactionViewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/transaction/get/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a single transaction by its ID from the database",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get a single transaction",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the transaction even if it is soft-deleted",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transaction details",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v1.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the transaction, for If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/transaction/restore/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Undoes a soft delete of a transaction",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Restore a deleted transaction",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Restored transaction",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v1.TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:void",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction is not deleted",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/transaction/update/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the qty of an existing transaction; the amount is recomputed at the price of the sale. The ID is taken from the path; an ID in the body is ignored",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Update an existing transaction",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the transaction as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Transaction information",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v1.TransactionUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated transaction",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v1.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated transaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction data",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction was modified since the version in the body, or was voided or refunded",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Transaction was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed, the transaction is not a sale, or the customer's balance or the item's stock does not cover a larger qty",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies an RFC 7396 JSON merge patch. Only qty can be changed; the amount follows from it at the price of the sale. Members that are absent keep their value. Returns the updated transaction as stored",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Partially update a transaction",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the transaction as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Members to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v1.TransactionUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated transaction",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v1.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated transaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID or patch",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction was modified since the version in the patch, or was voided or refunded",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Transaction was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed, the transaction is not a sale, or the customer's balance or the item's stock does not cover a larger qty",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves all transactions from the database",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get all transactions",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return every row as a plain array instead of a page (deprecated)",
+                        "name": "all",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted transactions",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of transactions",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-handlers_v1_TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the API keys without their secrets. Revoked keys are only listed with include_revoked",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "List API keys",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Include revoked keys",
+                        "name": "include_revoked",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "API keys",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers_v2.APIKeyResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid include_revoked parameter",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission api_keys:manage",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Generates an API key for a machine client. The key is only returned by this response; store it, as it cannot be retrieved again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Create an API key",
+                "parameters": [
+                    {
+                        "description": "API key information",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created API key, including the key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.CreatedAPIKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission api_keys:manage",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Requests using the key are rejected from then on",
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "API key revoked"
+                    },
+                    "400": {
+                        "description": "Invalid API key ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission api_keys:manage",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "API key is already revoked",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/customers": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "List customers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted customers",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of customers",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-lesson_handlers_v1_CustomerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "A non-zero balance is posted as the customer's first top-up",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Create a customer",
+                "parameters": [
+                    {
+                        "description": "Customer information",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.CustomerRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created customer",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.CustomerResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the created customer"
+                            },
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            },
+                            "Location": {
+                                "type": "string",
+                                "description": "Path of the created customer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/customers/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Get a customer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the customer even if it is soft-deleted",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Customer details",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.CustomerResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the customer, for If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The balance is read-only; it changes through top-ups and adjustments",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Replace a customer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the customer as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Customer information",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.CustomerUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated customer",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.CustomerResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated customer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID or malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Customer was modified since the version in the body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Customer was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft deletes a customer; it can be restored",
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Delete a customer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Customer deleted"
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:delete",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies an RFC 7396 JSON merge patch; members that are absent keep their value. The balance is read-only",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Partially update a customer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the customer as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Members to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.CustomerUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated customer",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.CustomerResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated customer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID or malformed patch",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Customer was modified since the version in the patch",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Customer was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/customers/{id}/balance-adjustments": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Corrects the balance by a signed amount and posts an adjustment with its reason to the balance ledger. The balance cannot go below zero",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "balance"
+                ],
+                "summary": "Adjust a customer's balance",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Adjustment",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.BalanceAdjustmentRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Posted balance entry",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.BalanceEntryResponse"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID or malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/customers/{id}/orders": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the orders of a customer with their lines, oldest first. Sales made before orders existed are one-line orders. Totals are net of the voids and refunds of the lines",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "List the orders of a customer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of orders",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-handlers_v2_OrderResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/customers/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Restore a deleted customer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Restored customer",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.CustomerResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the restored customer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:delete",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Customer is not deleted",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/customers/{id}/statement": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the opening balance at from, the balance entries posted from from up to to, and the closing balance at to. Deleted customers keep their statements",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "balance"
+                ],
+                "summary": "Get a customer's balance statement",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the period, an RFC 3339 timestamp or a YYYY-MM-DD date (default: the first entry)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the period, exclusive; a YYYY-MM-DD date includes that day (default: now)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Balance statement",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.BalanceStatementResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID or period",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/customers/{id}/topups": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Credits the balance and posts a top-up to the balance ledger",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "balance"
+                ],
+                "summary": "Top up a customer's balance",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Top-up",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.TopUpRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Posted balance entry",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.BalanceEntryResponse"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID or malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission customers:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/customers/{id}/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "List the transactions of a customer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted transactions, and answer for a soft-deleted customer",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of the customer's transactions",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-lesson_handlers_v1_TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/items": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "items"
+                ],
+                "summary": "List items",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted items",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of items",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-lesson_handlers_v1_ItemResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "items"
+                ],
+                "summary": "Create an item",
+                "parameters": [
+                    {
+                        "description": "Item information",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.ItemRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created item",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.ItemResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the created item"
+                            },
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            },
+                            "Location": {
+                                "type": "string",
+                                "description": "Path of the created item"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/items/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "items"
+                ],
+                "summary": "Get an item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the item even if it is soft-deleted",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Item details",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.ItemResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the item, for If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid item ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "items"
+                ],
+                "summary": "Replace an item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the item as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Item information",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.ItemUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated item",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.ItemResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated item"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid item ID or malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Item was modified since the version in the body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Item was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft deletes an item; it can be restored",
+                "tags": [
+                    "items"
+                ],
+                "summary": "Delete an item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Item deleted"
+                    },
+                    "400": {
+                        "description": "Invalid item ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:delete",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies an RFC 7396 JSON merge patch; members that are absent keep their value",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "items"
+                ],
+                "summary": "Partially update an item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the item as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Members to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.ItemUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated item",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.ItemResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated item"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid item ID or malformed patch",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Item was modified since the version in the patch",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Item was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed; see fields",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/items/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "items"
+                ],
+                "summary": "Restore a deleted item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Restored item",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.ItemResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the restored item"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid item ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:delete",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Item is not deleted",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/items/{id}/stock": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the quantity on hand. It goes below zero only for items that allow backorders; backordered counts the units sold that were not in stock",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stock"
+                ],
+                "summary": "Get the stock of an item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stock of the item",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.StockResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid item ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/items/{id}/stock-movements": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the stock ledger of an item, oldest first. Sales and returns of transactions link to the transaction; qty is the signed change of the stock",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stock"
+                ],
+                "summary": "List the stock movements of an item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of stock movements",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-handlers_v2_StockMovementResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid item ID or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Books a restock, return or adjustment of an item. Restocks and returns need a positive qty; adjustments a non-zero qty and a reason. Sales are booked by transactions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stock"
+                ],
+                "summary": "Book a stock movement",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Stock movement",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.StockMovementRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Booked stock movement",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.StockMovementResponse"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid item ID or malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission items:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed, invalid movement or insufficient stock",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/items/{id}/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "items"
+                ],
+                "summary": "List the transactions of an item",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted transactions, and answer for a soft-deleted item",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of the item's transactions",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-lesson_handlers_v1_TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid item ID or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Item not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/me/permissions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the roles of the API key or bearer token the request was made with and the permissions they grant. Needs no permission itself. When the server runs without authentication, method is none and both lists are empty",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "List the permissions of the caller",
+                "responses": {
+                    "200": {
+                        "description": "Roles and effective permissions",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.PermissionsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/orders": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Records the purchase of several items at once. Every line becomes a sale at the item's current price, debited from the customer's balance and booked in the stock ledger; the order is placed in full or not at all. Errors of a line name it, e.g. lines.1.item_id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Place an order",
+                "parameters": [
+                    {
+                        "description": "Customer and lines of the order",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.OrderRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Placed order",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.OrderResponse"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            },
+                            "Location": {
+                                "type": "string",
+                                "description": "Path of the placed order"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:create",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed, unknown customer or item, insufficient balance or insufficient stock",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/orders/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns an order with its lines and total. Deleted lines are left out; voids and refunds are separate transactions that name their line, and the total is net of them",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get an order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order with its lines",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.OrderResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid order ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Order not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/transaction-details": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "List transactions with customer and item details",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of transactions with customer and item details",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-lesson_handlers_v1_TransactionViewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/transaction-details/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Filters by ID, customer and item, creation date, amount and quantity ranges, with sorting",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Search transactions with customer and item details",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Customer IDs, comma separated or repeated",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Item IDs, comma separated or repeated",
+                        "name": "item_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive partial customer name",
+                        "name": "customer_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive partial item name",
+                        "name": "item_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Inclusive lower bound on created_at (RFC 3339 or YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Exclusive upper bound on created_at (RFC 3339 or YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum amount",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum amount",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum quantity",
+                        "name": "min_qty",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum quantity",
+                        "name": "max_qty",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "customer_name",
+                            "item_name",
+                            "qty",
+                            "price",
+                            "amount",
+                            "created_at"
+                        ],
+                        "type": "string",
+                        "description": "Sort column",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of matching transactions",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-lesson_handlers_v1_TransactionViewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "List transactions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted transactions",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of transactions",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_storage.Page-lesson_handlers_v1_TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Records a purchase as an order of one line: the amount is computed from the item's current price and debited from the customer's balance atomically. Use /v2/orders to buy several items at once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Create a transaction",
+                "parameters": [
+                    {
+                        "description": "Transaction information",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.TransactionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created transaction",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the created transaction"
+                            },
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            },
+                            "Location": {
+                                "type": "string",
+                                "description": "Path of the created transaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:create",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed, unknown customer or item, or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/transactions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get a transaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the transaction even if it is soft-deleted",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transaction details",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the transaction, for If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:read",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the qty of a sale; the amount is recomputed at the price of the sale and is read-only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Replace a transaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the transaction as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Transaction information",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.TransactionUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated transaction",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated transaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID or malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction was modified since the version in the body, or was voided or refunded",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Transaction was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed, the transaction is not a sale, or the customer's balance or the item's stock does not cover a larger qty",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft deletes a transaction; it can be restored. The customer's balance and the item's stock are not touched; void or refund a sale to reverse it",
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Delete a transaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Transaction deleted"
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:void",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies an RFC 7396 JSON merge patch; members that are absent keep their value. Only qty can be changed; the amount is recomputed at the price of the sale",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Partially update a transaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the transaction as last read; the update fails with 412 if it changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Members to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.TransactionUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated transaction",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated transaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID or malformed patch",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:write",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction was modified since the version in the patch, or was voided or refunded",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "412": {
+                        "description": "Transaction was modified since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed, the transaction is not a sale, or the customer's balance or the item's stock does not cover a larger qty",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/transactions/{id}/refunds": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Records a refund of qty units of a sale with a negative qty and amount, credits the customer and returns the units to stock. Units are refunded at the price of the sale; a sale can be refunded in several parts up to its qty",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Refund units of a sale",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the sale",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Units to refund and why",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.RefundRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "The refund",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the refund"
+                            },
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            },
+                            "Location": {
+                                "type": "string",
+                                "description": "Path of the refund"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID or malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:void",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "The sale was voided, or a request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed, the transaction is not a sale or qty exceeds the units not yet refunded",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/transactions/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Restore a deleted transaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Restored transaction",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the restored transaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:void",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction is not deleted",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/transactions/{id}/void": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reverses a sale of the current day in full: records a void with a negative qty and amount, credits the customer and returns the units to stock. Sales that were partly refunded or are from an earlier day must be refunded instead",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Void a sale",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the sale",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Why the sale is voided",
+                        "name": "input",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers_v2.VoidRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a repeat within the idempotency window returns the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "The void",
+                        "schema": {
+                            "$ref": "#/definitions/lesson_handlers_v1.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the void"
+                            },
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for a repeated Idempotency-Key"
+                            },
+                            "Location": {
+                                "type": "string",
+                                "description": "Path of the void"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID or malformed body",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing permission transactions:void",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "409": {
+                        "description": "The sale was already voided or refunded, or a request with the same Idempotency-Key is still being processed",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed, the transaction is not a sale or the sale is not from today",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    },
+                    "504": {
+                        "description": "Database query timed out",
+                        "schema": {
+                            "$ref": "#/definitions/storage.ResponseError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers_v1.CustomerRequest": {
+            "type": "object",
+            "required": [
+                "customer_name"
+            ],
+            "properties": {
+                "balance": {
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "1000.00"
+                },
+                "customer_name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "John Doe"
+                }
+            }
+        },
+        "handlers_v1.CustomerResponse": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "string",
+                    "example": "1000.00"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers_v1.CustomerUpdateRequest": {
+            "type": "object",
+            "required": [
+                "customer_name"
+            ],
+            "properties": {
+                "customer_name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "John Doe"
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                }
+            }
+        },
+        "handlers_v1.ItemRequest": {
+            "type": "object",
+            "required": [
+                "item_name"
+            ],
+            "properties": {
+                "allow_backorder": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "cost": {
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "950.00"
+                },
+                "item_name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Laptop"
+                },
+                "price": {
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "1200.00"
+                },
+                "sort": {
+                    "type": "integer",
+                    "example": 10
+                }
+            }
+        },
+        "handlers_v1.ItemResponse": {
+            "type": "object",
+            "properties": {
+                "allow_backorder": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "cost": {
+                    "type": "string",
+                    "example": "950.00"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "item_name": {
+                    "type": "string",
+                    "example": "Laptop"
+                },
+                "price": {
+                    "type": "string",
+                    "example": "1200.00"
+                },
+                "sort": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "stock": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers_v1.ItemUpdateRequest": {
+            "type": "object",
+            "required": [
+                "item_name"
+            ],
+            "properties": {
+                "allow_backorder": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "cost": {
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "950.00"
+                },
+                "item_name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Laptop"
+                },
+                "price": {
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "1200.00"
+                },
+                "sort": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                }
+            }
+        },
+        "handlers_v1.TransactionRequest": {
+            "type": "object",
+            "required": [
+                "customer_id",
+                "item_id",
+                "qty"
+            ],
+            "properties": {
+                "customer_id": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 1
+                },
+                "item_id": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 1
+                },
+                "qty": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 2
+                }
+            }
+        },
+        "handlers_v1.TransactionResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "2400.00"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "item_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "order_id": {
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 1
+                },
+                "original_transaction_id": {
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 1
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "wrong size"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "sale",
+                        "void",
+                        "refund"
+                    ],
+                    "example": "sale"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers_v1.TransactionUpdateRequest": {
+            "type": "object",
+            "required": [
+                "qty"
+            ],
+            "properties": {
+                "qty": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 2
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                }
+            }
+        },
+        "handlers_v1.TransactionViewResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "2400.00"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "customer_name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "item_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "item_name": {
+                    "type": "string",
+                    "example": "Laptop"
+                },
+                "original_transaction_id": {
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 1
+                },
+                "price": {
+                    "type": "string",
+                    "example": "1200.00"
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "sale",
+                        "void",
+                        "refund"
+                    ],
+                    "example": "sale"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers_v2.APIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "role"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "checkout terminal 3"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "cashier"
+                }
+            }
+        },
+        "handlers_v2.APIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "last_used_at": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "name": {
+                    "type": "string",
+                    "example": "checkout terminal 3"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "lk_3f9a0c5e21b4"
+                },
+                "revoked_at": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "role": {
+                    "type": "string",
+                    "example": "cashier"
+                }
+            }
+        },
+        "handlers_v2.BalanceAdjustmentRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "reason"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "-5.00"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "goodwill credit reversed"
+                }
+            }
+        },
+        "handlers_v2.BalanceEntryResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "50.00"
+                },
+                "balance_after": {
+                    "type": "string",
+                    "example": "1050.00"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "topup",
+                        "purchase",
+                        "refund",
+                        "adjustment"
+                    ],
+                    "example": "topup"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "cash at till 3"
+                },
+                "transaction_id": {
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 1
+                }
+            }
+        },
+        "handlers_v2.BalanceStatementResponse": {
+            "type": "object",
+            "properties": {
+                "closing_balance": {
+                    "type": "string",
+                    "example": "1050.00"
+                },
+                "customer_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers_v2.BalanceEntryResponse"
+                    }
+                },
+                "from": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "opening_balance": {
+                    "type": "string",
+                    "example": "1000.00"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers_v2.CreatedAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "key": {
+                    "type": "string",
+                    "example": "lk_3f9a0c5e21b4_Wm9vbGFuZGVyLXNlY3JldC1vbmx5LXNob3duLW9uY2U"
+                },
+                "last_used_at": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "name": {
+                    "type": "string",
+                    "example": "checkout terminal 3"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "lk_3f9a0c5e21b4"
+                },
+                "revoked_at": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "role": {
+                    "type": "string",
+                    "example": "cashier"
+                }
+            }
+        },
+        "handlers_v2.OrderLineRequest": {
+            "type": "object",
+            "required": [
+                "item_id",
+                "qty"
+            ],
+            "properties": {
+                "item_id": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 1
+                },
+                "qty": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 2
+                }
+            }
+        },
+        "handlers_v2.OrderLineResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "2400.00"
+                },
+                "item_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "price": {
+                    "type": "string",
+                    "example": "1200.00"
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "transaction_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers_v2.OrderRequest": {
+            "type": "object",
+            "required": [
+                "customer_id",
+                "lines"
+            ],
+            "properties": {
+                "customer_id": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 1
+                },
+                "lines": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/handlers_v2.OrderLineRequest"
+                    }
+                }
+            }
+        },
+        "handlers_v2.OrderResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers_v2.OrderLineResponse"
                     }
+                },
+                "total": {
+                    "type": "string",
+                    "example": "2425.00"
                 }
             }
-        }
-    },
-    "definitions": {
-        "storage.Customer": {
+        },
+        "handlers_v2.PermissionsResponse": {
             "type": "object",
             "properties": {
-                "balance": {
-                    "type": "number"
+                "method": {
+                    "type": "string",
+                    "enum": [
+                        "api_key",
+                        "jwt",
+                        "none"
+                    ],
+                    "example": "api_key"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "customers:read",
+                        "items:read",
+                        "transactions:create",
+                        "transactions:read"
+                    ]
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "cashier"
+                    ]
                 },
+                "subject": {
+                    "type": "string",
+                    "example": "api_key:1"
+                }
+            }
+        },
+        "handlers_v2.RefundRequest": {
+            "type": "object",
+            "required": [
+                "qty",
+                "reason"
+            ],
+            "properties": {
+                "qty": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 1
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "wrong size"
+                }
+            }
+        },
+        "handlers_v2.StockMovementRequest": {
+            "type": "object",
+            "required": [
+                "kind",
+                "qty"
+            ],
+            "properties": {
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "restock",
+                        "adjustment",
+                        "return"
+                    ],
+                    "example": "restock"
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "delivery 2024-118"
+                }
+            }
+        },
+        "handlers_v2.StockMovementResponse": {
+            "type": "object",
+            "properties": {
                 "created_at": {
                     "type": "string"
                 },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "item_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "sale",
+                        "restock",
+                        "adjustment",
+                        "return"
+                    ],
+                    "example": "sale"
+                },
+                "qty": {
+                    "type": "integer",
+                    "example": -2
+                },
+                "reason": {
+                    "type": "string",
+                    "example": ""
+                },
+                "stock_after": {
+                    "type": "integer",
+                    "example": 23
+                },
+                "transaction_id": {
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 1
+                }
+            }
+        },
+        "handlers_v2.StockResponse": {
+            "type": "object",
+            "properties": {
+                "allow_backorder": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "backordered": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "item_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "stock": {
+                    "type": "integer",
+                    "example": 25
+                }
+            }
+        },
+        "handlers_v2.TopUpRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "50.00"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "cash at till 3"
+                }
+            }
+        },
+        "handlers_v2.VoidRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "rung up twice"
+                }
+            }
+        },
+        "lesson_handlers_v1.CustomerRequest": {
+            "type": "object",
+            "required": [
+                "customer_name"
+            ],
+            "properties": {
+                "balance": {
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "1000.00"
+                },
                 "customer_name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "John Doe"
+                }
+            }
+        },
+        "lesson_handlers_v1.CustomerResponse": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "string",
+                    "example": "1000.00"
+                },
+                "created_at": {
                     "type": "string"
                 },
+                "customer_name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
                 "deleted_at": {
-                    "type": "string"
+                    "type": "string",
+                    "x-nullable": true
                 },
                 "id": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 1
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "lesson_handlers_v1.CustomerUpdateRequest": {
+            "type": "object",
+            "required": [
+                "customer_name"
+            ],
+            "properties": {
+                "customer_name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "John Doe"
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                }
+            }
+        },
+        "lesson_handlers_v1.ItemRequest": {
+            "type": "object",
+            "required": [
+                "item_name"
+            ],
+            "properties": {
+                "allow_backorder": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "cost": {
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "950.00"
+                },
+                "item_name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Laptop"
+                },
+                "price": {
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "1200.00"
+                },
+                "sort": {
+                    "type": "integer",
+                    "example": 10
                 }
             }
         },
-        "storage.Item": {
+        "lesson_handlers_v1.ItemResponse": {
             "type": "object",
             "properties": {
+                "allow_backorder": {
+                    "type": "boolean",
+                    "example": false
+                },
                 "cost": {
-                    "type": "number"
+                    "type": "string",
+                    "example": "950.00"
                 },
                 "created_at": {
                     "type": "string"
                 },
                 "deleted_at": {
-                    "type": "string"
+                    "type": "string",
+                    "x-nullable": true
                 },
                 "id": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 1
                 },
-                "name": {
-                    "type": "string"
+                "item_name": {
+                    "type": "string",
+                    "example": "Laptop"
                 },
                 "price": {
-                    "type": "number"
+                    "type": "string",
+                    "example": "1200.00"
                 },
                 "sort": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 10
+                },
+                "stock": {
+                    "type": "integer",
+                    "example": 25
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
                 }
             }
         },
-        "storage.ResponseError": {
+        "lesson_handlers_v1.ItemUpdateRequest": {
             "type": "object",
+            "required": [
+                "item_name"
+            ],
             "properties": {
-                "code": {
-                    "type": "integer"
+                "allow_backorder": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "cost": {
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "950.00"
+                },
+                "item_name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Laptop"
+                },
+                "price": {
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "1200.00"
+                },
+                "sort": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                }
+            }
+        },
+        "lesson_handlers_v1.TransactionRequest": {
+            "type": "object",
+            "required": [
+                "customer_id",
+                "item_id",
+                "qty"
+            ],
+            "properties": {
+                "customer_id": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 1
+                },
+                "item_id": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 1
                 },
-                "error": {}
+                "qty": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 2
+                }
             }
         },
-        "storage.Transaction": {
+        "lesson_handlers_v1.TransactionResponse": {
             "type": "object",
             "properties": {
                 "amount": {
-                    "type": "number"
+                    "type": "string",
+                    "example": "2400.00"
                 },
-                "createdAt": {
+                "created_at": {
                     "type": "string"
                 },
-                "customerID": {
-                    "type": "integer"
+                "customer_id": {
+                    "type": "integer",
+                    "example": 1
                 },
-                "deletedAt": {
-                    "type": "string"
+                "deleted_at": {
+                    "type": "string",
+                    "x-nullable": true
                 },
                 "id": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 1
+                },
+                "item_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "order_id": {
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 1
                 },
-                "itemID": {
-                    "type": "integer"
+                "original_transaction_id": {
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 1
                 },
                 "qty": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 2
                 },
-                "updatedAt": {
+                "reason": {
+                    "type": "string",
+                    "example": "wrong size"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "sale",
+                        "void",
+                        "refund"
+                    ],
+                    "example": "sale"
+                },
+                "updated_at": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
                 }
             }
         },
-        "storage.TransactionView": {
+        "lesson_handlers_v1.TransactionUpdateRequest": {
+            "type": "object",
+            "required": [
+                "qty"
+            ],
+            "properties": {
+                "qty": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 2
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                }
+            }
+        },
+        "lesson_handlers_v1.TransactionViewResponse": {
             "type": "object",
             "properties": {
                 "amount": {
-                    "type": "number"
+                    "type": "string",
+                    "example": "2400.00"
                 },
                 "created_at": {
                     "type": "string"
                 },
                 "customer_id": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 1
                 },
                 "customer_name": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "John Doe"
                 },
                 "deleted_at": {
-                    "type": "string"
+                    "type": "string",
+                    "x-nullable": true
                 },
                 "id": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 1
                 },
                 "item_id": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 1
                 },
                 "item_name": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "Laptop"
+                },
+                "original_transaction_id": {
+                    "type": "integer",
+                    "x-nullable": true,
+                    "example": 1
                 },
                 "price": {
-                    "type": "number"
+                    "type": "string",
+                    "example": "1200.00"
                 },
                 "qty": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 2
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "sale",
+                        "void",
+                        "refund"
+                    ],
+                    "example": "sale"
                 },
                 "updated_at": {
                     "type": "string"
                 }
             }
+        },
+        "lesson_storage.Page-handlers_v1_CustomerResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers_v1.CustomerResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "lesson_storage.Page-handlers_v1_ItemResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers_v1.ItemResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "lesson_storage.Page-handlers_v1_TransactionResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers_v1.TransactionResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "lesson_storage.Page-handlers_v1_TransactionViewResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers_v1.TransactionViewResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "lesson_storage.Page-handlers_v2_OrderResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers_v2.OrderResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "lesson_storage.Page-handlers_v2_StockMovementResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers_v2.StockMovementResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "lesson_storage.Page-lesson_handlers_v1_CustomerResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/lesson_handlers_v1.CustomerResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "lesson_storage.Page-lesson_handlers_v1_ItemResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/lesson_handlers_v1.ItemResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "lesson_storage.Page-lesson_handlers_v1_TransactionResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/lesson_handlers_v1.TransactionResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "lesson_storage.Page-lesson_handlers_v1_TransactionViewResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/lesson_handlers_v1.TransactionViewResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "storage.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "customer_name"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "customer_name is required"
+                },
+                "rule": {
+                    "type": "string",
+                    "example": "required"
+                }
+            }
+        },
+        "storage.ResponseError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "customer_not_found"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "customer not found"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.FieldError"
+                    }
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "API key created with POST /v2/api-keys or the apikey subcommand.",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "JWT issued by the identity provider, sent as \"Bearer \u003ctoken\u003e\".",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "",
+	Version:          "2.0",
 	Host:             "",
-	BasePath:         "",
+	BasePath:         "/",
 	Schemes:          []string{},
-	Title:            "",
-	Description:      "",
+	Title:            "Lesson shop API",
+	Description:      "Customers, items and the transactions between them. The /v1 routes are deprecated in favour of /v2.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"strings"
+
+	"lesson/auth"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// APIKeyHeader carries the API key of machine clients.
+	APIKeyHeader = "X-API-Key"
+	// PrincipalKey is the gin.Context key under which Authenticate stores the
+	// auth.Principal of the request.
+	PrincipalKey = "principal"
+)
+
+// Authenticate rejects requests without valid credentials with 401 and
+// stores the authenticated caller under PrincipalKey. Callers send an API key
+// in X-API-Key or a JWT in "Authorization: Bearer <token>"; a request sending
+// both is rejected rather than guessing which one was meant.
+func Authenticate(authenticator *auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := authenticate(c, authenticator)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="lesson"`)
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Set(PrincipalKey, principal)
+		c.Next()
+	}
+}
+
+func authenticate(c *gin.Context, authenticator *auth.Authenticator) (auth.Principal, error) {
+	key := c.GetHeader(APIKeyHeader)
+	authorization := c.GetHeader("Authorization")
+	switch {
+	case key != "" && authorization != "":
+		return auth.Principal{}, storage.UnauthenticatedError("send either " + APIKeyHeader + " or Authorization, not both")
+	case key != "":
+		return authenticator.APIKey(c.Request.Context(), key)
+	case authorization != "":
+		scheme, token, _ := strings.Cut(authorization, " ")
+		if !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return auth.Principal{}, storage.UnauthenticatedError("Authorization must use the Bearer scheme")
+		}
+		return authenticator.Bearer(c.Request.Context(), strings.TrimSpace(token))
+	}
+	return auth.Principal{}, storage.UnauthenticatedError("authentication required: send " + APIKeyHeader + " or a bearer token")
+}
+
+// Principal returns the caller Authenticate stored for the request. It
+// reports false when authentication is disabled.
+func Principal(c *gin.Context) (auth.Principal, bool) {
+	value, ok := c.Get(PrincipalKey)
+	if !ok {
+		return auth.Principal{}, false
+	}
+	principal, ok := value.(auth.Principal)
+	return principal, ok
+}
+
+// Require rejects requests whose principal lacks permission with 403, naming
+// the permission. It must run after Authenticate.
+func Require(permission auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := Principal(c)
+		if !ok || !principal.Can(permission) {
+			c.Error(storage.ForbiddenError(string(permission)))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// LegacyJSONHeader lets a client pick the member names of v1 transaction
+	// requests and responses: "legacy" for the PascalCase names or
+	// "snake_case".
+	LegacyJSONHeader = "X-API-JSON"
+	// LegacyJSONKey is the gin context key holding whether the request uses
+	// the legacy names.
+	LegacyJSONKey = "legacy_json"
+	// UnpaginatedListsKey is the gin context key holding whether list
+	// endpoints may answer all=true with every row.
+	UnpaginatedListsKey = "unpaginated_lists"
+)
+
+// LegacyJSON records in the context whether the request uses the legacy
+// member names, from the X-API-JSON header or else legacyDefault.
+func LegacyJSON(legacyDefault bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		legacy := legacyDefault
+		switch strings.ToLower(strings.TrimSpace(c.GetHeader(LegacyJSONHeader))) {
+		case "legacy":
+			legacy = true
+		case "snake_case":
+			legacy = false
+		}
+		c.Set(LegacyJSONKey, legacy)
+		c.Header(LegacyJSONHeader, map[bool]string{true: "legacy", false: "snake_case"}[legacy])
+		c.Next()
+	}
+}
+
+// UnpaginatedLists lets the list endpoints it guards answer all=true with
+// every row as a plain array, which /v1 clients written before lists were
+// paginated rely on. Elsewhere lists are always paginated.
+func UnpaginatedLists() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(UnpaginatedListsKey, true)
+		c.Next()
+	}
+}
@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"lesson/storage"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+// SpecPath turns a gin route pattern such as /v2/customers/:id into the
+// OpenAPI path /v2/customers/{id}.
+func SpecPath(route string) string {
+	return routeParam.ReplaceAllString(route, "{$1}")
+}
+
+// Contract checks traffic against the operation spec documents for the
+// route. With validateRequests, path and query parameters, headers and JSON
+// bodies are checked before the handler runs; a malformed parameter or body
+// is a 400 and a body that does not match its schema a 422 listing the
+// offending members. With validateResponses the response, including the
+// status, is checked after the handler ran and violations are logged, which
+// is meant for development. Routes spec does not document pass through.
+//
+// Merge patches and requests using the legacy transaction member names (see
+// LegacyJSON) are not described by the schemas, so their bodies are left to
+// the handlers.
+func Contract(spec *openapi3.T, validateRequests, validateResponses bool) gin.HandlerFunc {
+	routes := make(map[string]*routers.Route)
+	for path, item := range spec.Paths.Map() {
+		for method, operation := range item.Operations() {
+			routes[method+" "+path] = &routers.Route{Spec: spec, Path: path, PathItem: item, Method: method, Operation: operation}
+		}
+	}
+
+	return func(c *gin.Context) {
+		route, ok := routes[c.Request.Method+" "+SpecPath(c.FullPath())]
+		if !ok || !validateRequests && !validateResponses {
+			c.Next()
+			return
+		}
+		pathParams := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			pathParams[p.Key] = p.Value
+		}
+		skipBody := c.Request.Method == http.MethodPatch || c.GetBool(LegacyJSONKey)
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				ExcludeRequestBody:    skipBody,
+				ExcludeResponseBody:   c.GetBool(LegacyJSONKey),
+				IncludeResponseStatus: true,
+				MultiError:            true,
+				AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+				SkipSettingDefaults:   true,
+			},
+		}
+
+		if validateRequests {
+			if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+				c.Error(contractError(err))
+				c.Abort()
+				return
+			}
+		}
+		if !validateResponses {
+			c.Next()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status, header, body := recorder.Status(), recorder.Header(), recorder.body.Bytes()
+		if !recorder.Written() && len(c.Errors) > 0 {
+			// Errors renders the error once this middleware returns.
+			var rendered storage.ResponseError
+			status, rendered = Render(c.Errors.Last().Err)
+			body, _ = json.Marshal(rendered)
+			header = http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+		}
+		err := openapi3filter.ValidateResponse(c.Request.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 status,
+			Header:                 header,
+			Body:                   io.NopCloser(bytes.NewReader(body)),
+			Options:                input.Options,
+		})
+		if err != nil {
+			slog.Warn("response violates the API contract", "method", c.Request.Method, "route", route.Path, "status", status, "error", err)
+		}
+	}
+}
+
+// responseRecorder keeps a copy of the response body for validation.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// contractError turns the errors of ValidateRequest into domain errors:
+// parameters and unreadable bodies are invalid arguments, schema violations
+// of the body are reported per member.
+func contractError(err error) error {
+	var fields []storage.FieldError
+	for _, e := range flatten(err) {
+		switch e := e.(type) {
+		case *openapi3.SchemaError:
+			field := strings.Join(e.JSONPointer(), ".")
+			message := field + ": " + e.Reason
+			if e.SchemaField == "required" {
+				message = field + " is required"
+			}
+			fields = append(fields, storage.FieldError{Field: field, Rule: e.SchemaField, Message: message})
+		case *openapi3filter.RequestError:
+			if e.Parameter != nil {
+				return storage.InvalidParameterError(e.Parameter.Name, requestMessage(e))
+			}
+			return storage.InvalidArgumentError("invalid_body", "invalid request body: "+requestMessage(e))
+		default:
+			return storage.InvalidArgumentError("invalid_request", e.Error())
+		}
+	}
+	return storage.ValidationError(fields)
+}
+
+// flatten lists the errors of an openapi3.MultiError, or err itself. Schema
+// errors of a request body are listed on their own.
+func flatten(err error) []error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case openapi3.MultiError:
+		var errs []error
+		for _, inner := range e {
+			errs = append(errs, flatten(inner)...)
+		}
+		return errs
+	case *openapi3filter.RequestError:
+		if e.Parameter == nil {
+			switch e.Err.(type) {
+			case openapi3.MultiError, *openapi3.SchemaError:
+				return flatten(e.Err)
+			}
+		}
+	}
+	return []error{err}
+}
+
+func requestMessage(err *openapi3filter.RequestError) string {
+	if err.Err != nil {
+		return err.Err.Error()
+	}
+	return err.Reason
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated marks every response of a route group as deprecated since
+// deprecatedAt (RFC 9745) and, unless sunset is zero, as going away after
+// sunset (RFC 8594). successor is the path of the API replacing the group.
+func Deprecated(deprecatedAt, sunset time.Time, successor string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", deprecatedAt.Unix())
+	link := fmt.Sprintf("<%s>; rel=\"successor-version\"", successor)
+	var sunsetHeader string
+	if !sunset.IsZero() {
+		sunsetHeader = sunset.UTC().Format(http.TimeFormat)
+	}
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Link", link)
+		if sunsetHeader != "" {
+			c.Header("Sunset", sunsetHeader)
+		}
+		c.Next()
+	}
+}
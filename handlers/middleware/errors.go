@@ -0,0 +1,70 @@
+// Package middleware holds the gin middleware shared by every API version.
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Errors renders the last error a handler attached with c.Error as a
+// storage.ResponseError. Domain errors from the storage package map to their
+// status and code; anything else is logged and reported as an opaque 500 so
+// that driver messages never reach clients.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		status, body := Render(err)
+		if status == http.StatusInternalServerError || status == http.StatusGatewayTimeout {
+			log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		}
+		c.AbortWithStatusJSON(status, body)
+	}
+}
+
+// Render returns the status and body for err.
+func Render(err error) (int, storage.ResponseError) {
+	var domainErr *storage.Error
+	if !errors.As(err, &domainErr) {
+		return http.StatusInternalServerError, storage.ResponseError{Error: "internal server error", Code: "internal_error"}
+	}
+	return Status(domainErr), storage.ResponseError{Error: domainErr.Message, Code: domainErr.Code, Fields: domainErr.Fields}
+}
+
+// StatusClientClosedRequest is the non-standard status recorded when the
+// client went away before the response was written.
+const StatusClientClosedRequest = 499
+
+// Status maps a domain error kind to an HTTP status.
+func Status(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, storage.ErrCanceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, storage.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, storage.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrPrecondition):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, storage.ErrConstraint), errors.Is(err, storage.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, storage.ErrInvalidArgument):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
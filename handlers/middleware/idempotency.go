@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"lesson/auth"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader names a create request so that retrying it after
+	// a timeout does not create a second resource.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed from an earlier
+	// request with the same key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// replayedHeaders are stored with a response and sent again on replay.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", LegacyJSONHeader}
+
+// Idempotency makes requests sent with an Idempotency-Key safe to retry. The
+// first request with a key is processed and its response stored for ttl;
+// repeating it, identified by the principal, the key and a hash of the
+// method, path and body, returns the stored response. Without authentication
+// there is no principal to tell callers apart, so keys are shared by all.
+// Reusing the key for a different request is a 422, repeating it while the
+// first is still running a 409. Server errors are not stored, so that such
+// requests can be retried. Requests without the header are passed through.
+func Idempotency(store storage.IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.Error(storage.InvalidParameterError(IdempotencyKeyHeader, "must be at most "+strconv.Itoa(maxIdempotencyKeyLength)+" characters"))
+			c.Abort()
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(storage.InvalidArgumentError("invalid_body", "invalid request body: "+err.Error()))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := storage.IdempotencyRecord{Principal: idempotencyScope(c), Key: key, RequestHash: requestHash(c, body)}
+		existing, reserved, err := store.ReserveIdempotencyKey(c.Request.Context(), record, ttl)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		if !reserved {
+			replay(c, record, existing)
+			return
+		}
+
+		// The stored response must outlive the deadline of the request, and a
+		// handler that panics must not leave the key reserved until it expires.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), idempotencyStoreTimeout)
+		defer cancel()
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := store.ReleaseIdempotencyKey(ctx, record.Principal, record.Key); err != nil {
+				slog.Warn("releasing idempotency key failed", "key", record.Key, "error", err)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status, header, responseBody := recorder.Status(), recorder.Header(), recorder.body.Bytes()
+		if !recorder.Written() && len(c.Errors) > 0 {
+			// Errors renders the error once this middleware returns.
+			var rendered storage.ResponseError
+			status, rendered = Render(c.Errors.Last().Err)
+			responseBody, _ = json.Marshal(rendered)
+			header = http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+		}
+		if status >= http.StatusInternalServerError || status == StatusClientClosedRequest {
+			return
+		}
+		record.Status, record.Body, record.Header = status, responseBody, make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := header.Get(name); value != "" {
+				record.Header[name] = value
+			}
+		}
+		if err := store.CompleteIdempotencyKey(ctx, record); err != nil {
+			slog.Warn("storing idempotent response failed", "key", record.Key, "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replay answers a repeated request with the stored response of the first.
+func replay(c *gin.Context, request, stored storage.IdempotencyRecord) {
+	switch {
+	case !bytes.Equal(request.RequestHash, stored.RequestHash):
+		c.Error(storage.ErrIdempotencyKeyReused)
+	case stored.Status == 0:
+		c.Error(storage.ErrIdempotencyKeyInUse)
+	default:
+		for name, value := range stored.Header {
+			c.Header(name, value)
+		}
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Status(stored.Status)
+		c.Writer.Write(stored.Body)
+	}
+	c.Abort()
+}
+
+// requestHash identifies a request by its method, URI, body and the member
+// names its response uses.
+func requestHash(c *gin.Context, body []byte) []byte {
+	h := sha256.New()
+	io.WriteString(h, c.Request.Method+" "+c.Request.URL.RequestURI()+" legacy="+strconv.FormatBool(c.GetBool(LegacyJSONKey))+"\n")
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// idempotencyScope names the principal whose keys a request uses by how it
+// authenticated and its subject, so that a JWT whose sub reads "api_key:3"
+// cannot use the keys of API key 3.
+func idempotencyScope(c *gin.Context) string {
+	principal, ok := Principal(c)
+	if !ok {
+		principal.Method = auth.MethodNone
+	}
+	return string(principal.Method) + ":" + principal.Subject
+}
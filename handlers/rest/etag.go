@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetETag exposes the row version as a strong entity tag.
+func SetETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// Created answers a create with 201 Created and the new row's ETag, and with
+// a Location header when location, the path of the collection, is set.
+func Created(c *gin.Context, location string, id, version int, body interface{}) {
+	if location != "" {
+		c.Header("Location", location+"/"+strconv.Itoa(id))
+	}
+	SetETag(c, version)
+	c.JSON(http.StatusCreated, body)
+}
+
+// IfMatch reads the If-Match header of a conditional update. ok is false when
+// the header is absent. "*" matches any version and yields version 0.
+func IfMatch(c *gin.Context) (version int, ok bool, err error) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" {
+		return 0, false, nil
+	}
+	if value == "*" {
+		return 0, true, nil
+	}
+	tag, err := strconv.Unquote(value)
+	if err == nil {
+		version, err = strconv.Atoi(tag)
+	}
+	if err != nil || version < 1 {
+		return 0, false, storage.InvalidArgumentError("invalid_if_match", `If-Match must be a single entity tag such as "3" or *`)
+	}
+	return version, true, nil
+}
+
+// ConditionalError turns the conflict a store reports for a stale version
+// into 412 Precondition Failed when the client sent If-Match.
+func ConditionalError(err error, conditional bool, entity string) error {
+	if conditional && errors.Is(err, storage.ErrStaleVersion) {
+		return storage.PreconditionFailedError(entity)
+	}
+	return err
+}
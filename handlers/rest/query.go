@@ -0,0 +1,126 @@
+package rest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const dateLayout = "2006-01-02"
+
+func InvalidParam(name, message string) error {
+	return storage.InvalidParameterError(name, message)
+}
+
+func InvalidID(entity string) error {
+	return storage.InvalidArgumentError("invalid_id", fmt.Sprintf("Invalid %s ID", entity))
+}
+
+func InvalidBody(err error) error {
+	return storage.InvalidArgumentError("invalid_body", "invalid request body: "+err.Error())
+}
+
+func QueryInt(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, InvalidParam(name, "must be an integer")
+	}
+	return n, nil
+}
+
+func QueryIntPtr(c *gin.Context, name string) (*int, error) {
+	if c.Query(name) == "" {
+		return nil, nil
+	}
+	n, err := QueryInt(c, name)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func QueryMoneyPtr(c *gin.Context, name string) (*storage.Money, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	m, err := storage.ParseMoney(value)
+	if err != nil {
+		return nil, InvalidParam(name, "must be a decimal amount with at most two decimal places")
+	}
+	return &m, nil
+}
+
+// QueryIntList accepts both repeated parameters and comma separated values.
+func QueryIntList(c *gin.Context, name string) ([]int, error) {
+	var list []int
+	for _, value := range c.QueryArray(name) {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, InvalidParam(name, fmt.Sprintf("%q is not an integer", part))
+			}
+			list = append(list, n)
+		}
+	}
+	return list, nil
+}
+
+// QueryTime accepts RFC 3339 timestamps and plain dates. With endOfDay set, a
+// plain date is moved to the start of the following day so that it can be
+// used as an exclusive upper bound that still covers the whole date.
+func QueryTime(c *gin.Context, name string, endOfDay bool) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, InvalidParam(name, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+func QueryBool(c *gin.Context, name string) (bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, InvalidParam(name, "must be a boolean")
+	}
+	return b, nil
+}
+
+// PageRequest reads the limit and cursor parameters of list endpoints.
+func PageRequest(c *gin.Context) (page storage.PageRequest, err error) {
+	page.Limit = storage.DefaultPageLimit
+	if c.Query("limit") != "" {
+		page.Limit, err = strconv.Atoi(c.Query("limit"))
+		if err != nil || page.Limit < 1 || page.Limit > storage.MaxPageLimit {
+			return page, InvalidParam("limit", fmt.Sprintf("must be an integer between 1 and %d", storage.MaxPageLimit))
+		}
+	}
+	page.Cursor = c.Query("cursor")
+	return page, nil
+}
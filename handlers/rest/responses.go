@@ -0,0 +1,17 @@
+package rest
+
+import "lesson/storage"
+
+// MapSlice converts every element of a store result with to.
+func MapSlice[S, D any](rows []S, to func(S) D) []D {
+	mapped := make([]D, len(rows))
+	for i, row := range rows {
+		mapped[i] = to(row)
+	}
+	return mapped
+}
+
+// MapPage converts every row of a page with to, keeping the cursor.
+func MapPage[S, D any](page storage.Page[S], to func(S) D) storage.Page[D] {
+	return storage.Page[D]{Data: MapSlice(page.Data, to), NextCursor: page.NextCursor}
+}
@@ -0,0 +1,324 @@
+// Package rest holds the request binding, query parameter, conditional
+// request and response helpers that the handlers of every API version share.
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// readOnlyFields are members of responses that clients commonly echo back but
+// may not set. They are reported as "read_only" rather than "unknown".
+var readOnlyFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+	"version":    true,
+	"ID":         true,
+	"CreatedAt":  true,
+	"UpdatedAt":  true,
+	"DeletedAt":  true,
+	"Version":    true,
+	"amount":     true,
+	"Amount":     true,
+	"stock":      true,
+	"balance":    true,
+}
+
+// requestField is a member of a request type. rules is its binding tag in
+// go-playground/validator syntax.
+type requestField struct {
+	name  string
+	index []int
+	rules string
+}
+
+var setupValidator sync.Once
+
+// validate returns gin's validator, set up to check storage.Money with the
+// numeric rules such as gte=0.
+func validate() *validator.Validate {
+	v := binding.Validator.Engine().(*validator.Validate)
+	setupValidator.Do(func() {
+		v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+			return field.Interface().(storage.Money).InexactFloat64()
+		}, storage.Money{})
+	})
+	return v
+}
+
+// requestFields lists the JSON members of the request struct t, including
+// those of embedded structs.
+func requestFields(t reflect.Type) []requestField {
+	var fields []requestField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" {
+			for _, inner := range requestFields(f.Type) {
+				inner.index = append([]int{i}, inner.index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, requestField{name: name, index: []int{i}, rules: f.Tag.Get("binding")})
+	}
+	return fields
+}
+
+// BindJSON decodes the request body into dst, a pointer to a request struct,
+// and validates every field.
+func BindJSON(c *gin.Context, dst interface{}) error {
+	_, err := DecodeRequest(c, dst, false)
+	return err
+}
+
+// BindMergePatch decodes an RFC 7396 merge patch into dst, a pointer to a
+// request struct, and validates the members it sets. It returns the names of
+// those members; absent members keep their stored value. A null member would
+// remove the field, which none of the columns allow.
+func BindMergePatch(c *gin.Context, dst interface{}) (present map[string]bool, err error) {
+	return DecodeRequest(c, dst, true)
+}
+
+// DecodeRequest decodes a JSON object member by member so that every problem
+// can be reported against the member that caused it. Syntax errors are
+// invalid arguments; everything else is collected into one ValidationError.
+func DecodeRequest(c *gin.Context, dst interface{}, partial bool) (map[string]bool, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, InvalidBody(err)
+	}
+	var members map[string]json.RawMessage
+	var typeErr *json.UnmarshalTypeError
+	err = json.Unmarshal(body, &members)
+	if errors.As(err, &typeErr) || err == nil && members == nil {
+		return nil, InvalidBody(errors.New("the body must be a JSON object"))
+	}
+	if err != nil {
+		return nil, InvalidBody(err)
+	}
+
+	target := reflect.ValueOf(dst).Elem()
+	fields := requestFields(target.Type())
+	present, fieldErrors := decodeMembers("", members, target, fields, partial)
+	if len(fieldErrors) > 0 {
+		sortFieldErrors(fieldErrors, fields)
+		return nil, storage.ValidationError(fieldErrors)
+	}
+	return present, nil
+}
+
+// decodeMembers decodes members into the fields of target and validates
+// them. prefix is prepended to the names of members in the errors, so that
+// the elements of a list of objects report e.g. lines.0.qty, like the
+// OpenAPI request validation does.
+func decodeMembers(prefix string, members map[string]json.RawMessage, target reflect.Value, fields []requestField, partial bool) (map[string]bool, []storage.FieldError) {
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.name] = true
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fieldErrors []storage.FieldError
+	for _, name := range names {
+		switch {
+		case known[name]:
+		case readOnlyFields[name]:
+			fieldErrors = append(fieldErrors, storage.FieldError{Field: prefix + name, Rule: "read_only", Message: prefix + name + " is read-only"})
+		default:
+			fieldErrors = append(fieldErrors, storage.FieldError{Field: prefix + name, Rule: "unknown", Message: prefix + name + " is not a known field"})
+		}
+	}
+
+	present := make(map[string]bool)
+	for _, f := range fields {
+		raw, ok := members[f.name]
+		named := f
+		named.name = prefix + f.name
+		if !ok {
+			if !partial {
+				fieldErrors = append(fieldErrors, checkField(named, target.FieldByIndex(f.index))...)
+			}
+			continue
+		}
+		if partial && string(raw) == "null" {
+			fieldErrors = append(fieldErrors, storage.FieldError{Field: named.name, Rule: "not_null", Message: named.name + " cannot be null"})
+			continue
+		}
+		value := target.FieldByIndex(f.index)
+		if err := json.Unmarshal(raw, value.Addr().Interface()); err != nil {
+			fieldErrors = append(fieldErrors, storage.FieldError{Field: named.name, Rule: "type", Message: typeMessage(named.name, err)})
+			continue
+		}
+		present[f.name] = true
+		fieldErrors = append(fieldErrors, checkField(named, value)...)
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct {
+			fieldErrors = append(fieldErrors, decodeElements(named.name, raw, value)...)
+		}
+	}
+	return present, fieldErrors
+}
+
+// decodeElements validates the objects of the list member name one by one,
+// like DecodeRequest validates a body. The list itself was decoded already.
+func decodeElements(name string, raw json.RawMessage, list reflect.Value) []storage.FieldError {
+	var elements []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &elements); err != nil {
+		return nil
+	}
+	fields := requestFields(list.Type().Elem())
+	var fieldErrors []storage.FieldError
+	for i, members := range elements {
+		_, elementErrors := decodeMembers(fmt.Sprintf("%s.%d.", name, i), members, list.Index(i), fields, false)
+		fieldErrors = append(fieldErrors, elementErrors...)
+	}
+	return fieldErrors
+}
+
+func checkField(f requestField, value reflect.Value) []storage.FieldError {
+	if f.rules == "" {
+		return nil
+	}
+	err := validate().Var(value.Interface(), f.rules)
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+	fieldErrors := make([]storage.FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fieldErrors = append(fieldErrors, storage.FieldError{Field: f.name, Rule: fe.Tag(), Message: ruleMessage(f.name, fe)})
+	}
+	return fieldErrors
+}
+
+func ruleMessage(name string, fe validator.FieldError) string {
+	text := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required":
+		return name + " is required"
+	case "min":
+		if text {
+			return fmt.Sprintf("%s must be at least %s characters long", name, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", name, fe.Param())
+	case "max":
+		if text {
+			return fmt.Sprintf("%s must be at most %s characters long", name, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", name, fe.Param())
+	case "gte":
+		if fe.Param() == "0" {
+			return name + " must not be negative"
+		}
+		return fmt.Sprintf("%s must be at least %s", name, fe.Param())
+	case "gt":
+		if fe.Param() == "0" {
+			return name + " must be positive"
+		}
+		return fmt.Sprintf("%s must be greater than %s", name, fe.Param())
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s", name, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", name, strings.ReplaceAll(fe.Param(), " ", ", "))
+	}
+	return fmt.Sprintf("%s fails the %s rule", name, fe.Tag())
+}
+
+func typeMessage(name string, err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Sprintf("%s must be %s", name, jsonKind(typeErr.Type))
+	}
+	return fmt.Sprintf("%s: %v", name, err)
+}
+
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	}
+	return "a valid value"
+}
+
+// sortFieldErrors orders errors like the request type declares its fields,
+// with members the type does not have last, so that responses are stable.
+func sortFieldErrors(fieldErrors []storage.FieldError, fields []requestField) {
+	order := make(map[string]int, len(fields))
+	for i, f := range fields {
+		order[f.name] = i
+	}
+	rank := func(fe storage.FieldError) int {
+		// Errors of list elements, such as lines.0.qty, rank with the list.
+		name, _, _ := strings.Cut(fe.Field, ".")
+		if i, ok := order[name]; ok {
+			return i
+		}
+		return len(fields)
+	}
+	sort.SliceStable(fieldErrors, func(i, j int) bool {
+		ri, rj := rank(fieldErrors[i]), rank(fieldErrors[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return ri == len(fields) && fieldErrors[i].Field < fieldErrors[j].Field
+	})
+}
+
+// ReferenceError reports a purchase of a customer or item that does not exist
+// as a validation error of the member that names it. Items of an order are
+// named by their line; otherwise member, when set, gives the name the request
+// uses for a member.
+func ReferenceError(err error, member func(name string) string) error {
+	var domainErr *storage.Error
+	if !errors.As(err, &domainErr) {
+		return err
+	}
+	var field, entity string
+	switch domainErr.Code {
+	case "customer_not_found":
+		field, entity = "customer_id", "customer"
+	case "item_not_found":
+		field, entity = "item_id", "item"
+	default:
+		return err
+	}
+	var lineErr *storage.LineError
+	if errors.As(err, &lineErr) {
+		field = fmt.Sprintf("lines.%d.%s", lineErr.Line, field)
+	} else if member != nil {
+		field = member(field)
+	}
+	return storage.ValidationError([]storage.FieldError{{Field: field, Rule: "exists", Message: field + " must name an existing " + entity}})
+}
@@ -1,41 +1,177 @@
 package api
 
 import (
-	"database/sql"
+	"fmt"
+	"time"
+
+	"lesson/auth"
+	"lesson/config"
+	"lesson/handlers/middleware"
 	v1 "lesson/handlers/v1"
+	v2 "lesson/handlers/v2"
+	"lesson/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(db *sql.DB) *gin.Engine {
+// v1Deprecated is when /v2 superseded /v1, announced in the Deprecation header
+// of every /v1 response.
+var v1Deprecated = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+func SetupRouter(cfg config.Config, customers storage.CustomerStore, items storage.ItemStore, transactions storage.TransactionStore, orders storage.OrderStore, balances storage.BalanceStore, stock storage.StockStore, apiKeys storage.APIKeyStore, roles storage.RoleStore, idempotency storage.IdempotencyStore) *gin.Engine {
 	r := gin.Default()
 
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
+	r.Use(middleware.Errors())
+	r.Use(middleware.QueryTimeout(cfg.Query.Timeout.Duration(), cfg.Query.Routes()))
+	registerDocs(r)
+
+	spec, err := loadSpec()
+	if err != nil {
+		panic(err)
+	}
+	contract := middleware.Contract(spec, cfg.OpenAPI.ValidateRequests, cfg.OpenAPI.ValidateResponses)
+	authenticate, err := authentication(cfg.Auth, apiKeys, roles)
+	if err != nil {
+		panic(err)
+	}
+	require := middleware.Require
+	if !cfg.Auth.Enabled {
+		require = func(auth.Permission) gin.HandlerFunc { return pass }
+	}
+	// allow returns the routes of group that need permission. Callers are
+	// authenticated and authorized before the contract is checked, so that
+	// they learn nothing from validation errors of requests they may not make.
+	allow := func(group *gin.RouterGroup, permission auth.Permission) *gin.RouterGroup {
+		return group.Group("", require(permission), contract)
+	}
+	// allowCreate is allow for creates, which also honour Idempotency-Key.
+	idempotent := middleware.Idempotency(idempotency, cfg.Idempotency.TTL.Duration())
+	allowCreate := func(group *gin.RouterGroup, permission auth.Permission) *gin.RouterGroup {
+		return group.Group("", require(permission), idempotent, contract)
+	}
+
+	// v1 predates the resource routes of v2 and is kept for existing clients.
+	// Only its transaction routes may use the legacy member names, which must
+	// be decided before the contract is checked.
+	sunset, _ := cfg.Sunset()
+	api := r.Group("/v1", middleware.Deprecated(v1Deprecated, sunset, "/v2"), authenticate, middleware.UnpaginatedLists())
+	legacyAPI := api.Group("", middleware.LegacyJSON(cfg.LegacyJSON))
+
+	customerHandler := v1.NewCustomerHandler(customers)
+	allow(api, auth.CustomersRead).GET("/customers", customerHandler.GetCustomers)
+	allowCreate(api, auth.CustomersWrite).POST("/customer/create", customerHandler.CreateCustomer)
+	allow(api, auth.CustomersWrite).PUT("/customer/update/:id", customerHandler.UpdateCustomer)
+	allow(api, auth.CustomersWrite).PATCH("/customer/update/:id", customerHandler.PatchCustomer)
+	allow(api, auth.CustomersDelete).DELETE("/customer/delete/:id", customerHandler.DeleteCustomer)
+	allow(api, auth.CustomersDelete).POST("/customer/restore/:id", customerHandler.RestoreCustomer)
+	allow(api, auth.CustomersRead).GET("/customer/get/:id", customerHandler.GetCustomer)
+
+	itemHandler := v1.NewItemHandler(items)
+	allow(api, auth.ItemsRead).GET("/items", itemHandler.GetItems)
+	allowCreate(api, auth.ItemsWrite).POST("/item/create", itemHandler.CreateItem)
+	allow(api, auth.ItemsWrite).PUT("/item/update/:id", itemHandler.UpdateItem)
+	allow(api, auth.ItemsWrite).PATCH("/item/update/:id", itemHandler.PatchItem)
+	allow(api, auth.ItemsDelete).DELETE("/item/delete/:id", itemHandler.DeleteItem)
+	allow(api, auth.ItemsDelete).POST("/item/restore/:id", itemHandler.RestoreItem)
+	allow(api, auth.ItemsRead).GET("/item/get/:id", itemHandler.GetItem)
+
+	transactionHandler := v1.NewTransactionHandler(transactions)
+	allow(legacyAPI, auth.TransactionsRead).GET("/transactions", transactionHandler.GetTransactions)
+	allowCreate(legacyAPI, auth.TransactionsCreate).POST("/transaction/create", transactionHandler.CreateTransaction)
+	allow(legacyAPI, auth.TransactionsWrite).PUT("/transaction/update/:id", transactionHandler.UpdateTransaction)
+	allow(legacyAPI, auth.TransactionsWrite).PATCH("/transaction/update/:id", transactionHandler.PatchTransaction)
+	allow(legacyAPI, auth.TransactionsVoid).DELETE("/transaction/delete/:id", transactionHandler.DeleteTransaction)
+	allow(legacyAPI, auth.TransactionsVoid).POST("/transaction/restore/:id", transactionHandler.RestoreTransaction)
+	allow(legacyAPI, auth.TransactionsRead).GET("/transaction/get/:id", transactionHandler.GetTransaction)
+	allow(legacyAPI, auth.TransactionsRead).GET("/transaction/details", transactionHandler.GetTransactionDetailsWithCustomerAndItem)
+	allow(legacyAPI, auth.TransactionsRead).GET("/transaction/filter", transactionHandler.FilterTransactions)
+
+	resources := r.Group("/v2", authenticate)
+
+	customersV2 := v2.NewCustomerHandler(customers)
+	allow(resources, auth.CustomersRead).GET("/customers", customersV2.GetCustomers)
+	allowCreate(resources, auth.CustomersWrite).POST("/customers", customersV2.CreateCustomer)
+	allow(resources, auth.CustomersRead).GET("/customers/:id", customersV2.GetCustomer)
+	allow(resources, auth.CustomersWrite).PUT("/customers/:id", customersV2.UpdateCustomer)
+	allow(resources, auth.CustomersWrite).PATCH("/customers/:id", customersV2.PatchCustomer)
+	allow(resources, auth.CustomersDelete).DELETE("/customers/:id", customersV2.DeleteCustomer)
+	allow(resources, auth.CustomersDelete).POST("/customers/:id/restore", customersV2.RestoreCustomer)
+
+	balancesV2 := v2.NewBalanceHandler(balances)
+	allowCreate(resources, auth.CustomersWrite).POST("/customers/:id/topups", balancesV2.TopUp)
+	allowCreate(resources, auth.CustomersWrite).POST("/customers/:id/balance-adjustments", balancesV2.AdjustBalance)
+	allow(resources, auth.CustomersRead).GET("/customers/:id/statement", balancesV2.GetStatement)
 
-	api := r.Group("/v1")
-
-	customerHandler := v1.NewCustomerHandler(db)
-	api.GET("/customers", customerHandler.GetCustomers)
-	api.POST("/customer/create", customerHandler.CreateCustomer)
-	api.PUT("/customer/update/:id", customerHandler.UpdateCustomer)
-	api.DELETE("/customer/delete/:id", customerHandler.DeleteCustomer)
-	api.GET("/customer/get/:id", customerHandler.GetCustomer)
-
-	itemHandler := v1.NewItemHandler(db)
-	api.GET("/items", itemHandler.GetItems)
-	api.POST("/item/create", itemHandler.CreateItem)
-	api.PUT("/item/update/:id", itemHandler.UpdateItem)
-	api.DELETE("/item/delete/:id", itemHandler.DeleteItem)
-	api.GET("/item/get/:id", itemHandler.GetItem)
-
-	transactionHandler := v1.NewTransactionHandler(db)
-	api.GET("/transactions", transactionHandler.GetTransactions)
-	api.POST("/transaction/create", transactionHandler.CreateTransaction)
-	api.PUT("/transaction/update/:id", transactionHandler.UpdateTransaction)
-	api.DELETE("/transaction/delete/:id", transactionHandler.DeleteTransaction)
-	api.GET("/transaction/get/:id", transactionHandler.GetTransaction)
-	api.GET("/transaction/details", transactionHandler.GetTransactionDetailsWithCustomerAndItem)
-	api.GET("/transaction/filter", transactionHandler.FilterTransactions)
+	itemsV2 := v2.NewItemHandler(items)
+	allow(resources, auth.ItemsRead).GET("/items", itemsV2.GetItems)
+	allowCreate(resources, auth.ItemsWrite).POST("/items", itemsV2.CreateItem)
+	allow(resources, auth.ItemsRead).GET("/items/:id", itemsV2.GetItem)
+	allow(resources, auth.ItemsWrite).PUT("/items/:id", itemsV2.UpdateItem)
+	allow(resources, auth.ItemsWrite).PATCH("/items/:id", itemsV2.PatchItem)
+	allow(resources, auth.ItemsDelete).DELETE("/items/:id", itemsV2.DeleteItem)
+	allow(resources, auth.ItemsDelete).POST("/items/:id/restore", itemsV2.RestoreItem)
+
+	stockV2 := v2.NewStockHandler(items, stock)
+	allow(resources, auth.ItemsRead).GET("/items/:id/stock", stockV2.GetStock)
+	allow(resources, auth.ItemsRead).GET("/items/:id/stock-movements", stockV2.GetStockMovements)
+	allowCreate(resources, auth.ItemsWrite).POST("/items/:id/stock-movements", stockV2.AddStockMovement)
+
+	transactionsV2 := v2.NewTransactionHandler(transactions)
+	allow(resources, auth.TransactionsRead).GET("/transactions", transactionsV2.GetTransactions)
+	allowCreate(resources, auth.TransactionsCreate).POST("/transactions", transactionsV2.CreateTransaction)
+	allow(resources, auth.TransactionsRead).GET("/transactions/:id", transactionsV2.GetTransaction)
+	allow(resources, auth.TransactionsWrite).PUT("/transactions/:id", transactionsV2.UpdateTransaction)
+	allow(resources, auth.TransactionsWrite).PATCH("/transactions/:id", transactionsV2.PatchTransaction)
+	allow(resources, auth.TransactionsVoid).DELETE("/transactions/:id", transactionsV2.DeleteTransaction)
+	allow(resources, auth.TransactionsVoid).POST("/transactions/:id/restore", transactionsV2.RestoreTransaction)
+	allowCreate(resources, auth.TransactionsVoid).POST("/transactions/:id/void", transactionsV2.VoidTransaction)
+	allowCreate(resources, auth.TransactionsVoid).POST("/transactions/:id/refunds", transactionsV2.RefundTransaction)
+	allow(resources, auth.TransactionsRead).GET("/customers/:id/transactions", transactionsV2.GetCustomerTransactions)
+	allow(resources, auth.TransactionsRead).GET("/items/:id/transactions", transactionsV2.GetItemTransactions)
+	allow(resources, auth.TransactionsRead).GET("/transaction-details", transactionsV2.GetTransactionDetails)
+	allow(resources, auth.TransactionsRead).GET("/transaction-details/search", transactionsV2.SearchTransactionDetails)
+
+	ordersV2 := v2.NewOrderHandler(orders)
+	allowCreate(resources, auth.TransactionsCreate).POST("/orders", ordersV2.CreateOrder)
+	allow(resources, auth.TransactionsRead).GET("/orders/:id", ordersV2.GetOrder)
+	allow(resources, auth.TransactionsRead).GET("/customers/:id/orders", ordersV2.GetCustomerOrders)
+
+	// Creating an API key is not idempotent: storing the response would store
+	// the key.
+	apiKeysV2 := v2.NewAPIKeyHandler(apiKeys)
+	allow(resources, auth.APIKeysManage).GET("/api-keys", apiKeysV2.GetAPIKeys)
+	allow(resources, auth.APIKeysManage).POST("/api-keys", apiKeysV2.CreateAPIKey)
+	allow(resources, auth.APIKeysManage).DELETE("/api-keys/:id", apiKeysV2.RevokeAPIKey)
+
+	// Every authenticated caller may ask what it is allowed to do.
+	principalV2 := v2.NewPrincipalHandler()
+	resources.Group("", contract).GET("/me/permissions", principalV2.GetPermissions)
 	return r
 }
+
+// pass lets a request through; it stands in for disabled checks.
+func pass(c *gin.Context) {
+	c.Next()
+}
+
+// authentication returns the middleware that authenticates API requests, or
+// one letting every request through when auth is disabled.
+func authentication(cfg config.Auth, apiKeys storage.APIKeyStore, roles storage.RoleStore) (gin.HandlerFunc, error) {
+	if !cfg.Enabled {
+		return pass, nil
+	}
+	var verifier *auth.JWTVerifier
+	publicKey, err := cfg.JWT.RSAPublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("auth.jwt.public_key: %w", err)
+	}
+	if cfg.JWT.Secret != "" || publicKey != nil {
+		verifier, err = auth.NewJWTVerifier([]byte(cfg.JWT.Secret), publicKey, cfg.JWT.Issuer, cfg.JWT.Audience)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return middleware.Authenticate(auth.NewAuthenticator(apiKeys, roles, verifier)), nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	_ "lesson/docs"
+	"lesson/handlers/middleware"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/swaggo/swag"
+)
+
+// docsPath is where the OpenAPI document (doc.json) and Swagger UI are
+// served. It is left out of the spec itself.
+const docsPath = "/docs"
+
+func registerDocs(r *gin.Engine) {
+	r.GET(docsPath, func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, docsPath+"/index.html")
+	})
+	r.GET(docsPath+"/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+}
+
+// loadSpec returns the generated Swagger 2.0 document converted to OpenAPI 3,
+// which the contract middleware validates against.
+func loadSpec() (*openapi3.T, error) {
+	doc, err := swag.ReadDoc()
+	if err != nil {
+		return nil, fmt.Errorf("read spec: %w", err)
+	}
+	var v2 openapi2.T
+	if err := json.Unmarshal([]byte(doc), &v2); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	spec, err := openapi2conv.ToV3(&v2)
+	if err != nil {
+		return nil, fmt.Errorf("convert spec: %w", err)
+	}
+	return spec, nil
+}
+
+// SpecDrift compares the routes registered on r with the operations of the
+// generated OpenAPI document and describes each one that only one side has,
+// e.g. "GET /v1/customer/get/{id}: registered but not in the spec".
+func SpecDrift(r *gin.Engine) ([]string, error) {
+	doc, err := swag.ReadDoc()
+	if err != nil {
+		return nil, fmt.Errorf("read spec: %w", err)
+	}
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal([]byte(doc), &spec); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	documented := make(map[string]bool)
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		if route.Path == docsPath || strings.HasPrefix(route.Path, docsPath+"/") {
+			continue
+		}
+		registered[route.Method+" "+middleware.SpecPath(route.Path)] = true
+	}
+
+	var drift []string
+	for operation := range registered {
+		if !documented[operation] {
+			drift = append(drift, operation+": registered but not in the spec")
+		}
+	}
+	for operation := range documented {
+		if !registered[operation] {
+			drift = append(drift, operation+": in the spec but not registered")
+		}
+	}
+	sort.Strings(drift)
+	return drift, nil
+}
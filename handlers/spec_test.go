@@ -0,0 +1,24 @@
+package api
+
+import (
+	"testing"
+
+	"lesson/config"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSpecDrift(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	r := SetupRouter(config.Default(), store, store, store, store, store, store, store, store, store)
+
+	drift, err := SpecDrift(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range drift {
+		t.Error(d)
+	}
+}
@@ -1,21 +1,30 @@
 package v1
 
 import (
-	"database/sql"
 	"net/http"
 	"strconv"
 
+	"lesson/handlers/rest"
 	"lesson/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
 type CustomerHandler struct {
-	db *sql.DB
+	store    storage.CustomerStore
+	location string
 }
 
-func NewCustomerHandler(db *sql.DB) *CustomerHandler {
-	return &CustomerHandler{db: db}
+func NewCustomerHandler(store storage.CustomerStore) *CustomerHandler {
+	return &CustomerHandler{store: store}
+}
+
+// WithLocation returns a copy of h that answers creates with a Location
+// header of path/{id}.
+func (h *CustomerHandler) WithLocation(path string) *CustomerHandler {
+	copied := *h
+	copied.location = path
+	return &copied
 }
 
 // CreateCustomer godoc
@@ -24,23 +33,33 @@ func NewCustomerHandler(db *sql.DB) *CustomerHandler {
 // @Tags customers
 // @Accept json
 // @Produce json
-// @Param input body storage.Customer true "Customer information"
-// @Success 201 {object} storage.Customer "Created customer"
+// @Param input body v1.CustomerRequest true "Customer information"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v1.CustomerResponse "Created customer"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
 // @Failure 400 {object} storage.ResponseError "Invalid customer data"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:write"
+// @Failure 409 {object} storage.ResponseError "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/customer/create [post]
 func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
-	var customer storage.Customer
-	if err := c.ShouldBindJSON(&customer); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var request CustomerRequest
+	if err := rest.BindJSON(c, &request); err != nil {
+		c.Error(err)
 		return
 	}
-	createdCustomer, err := storage.CreateCustomer(h.db, customer)
+	createdCustomer, err := h.store.CreateCustomer(c.Request.Context(), request.customer())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusCreated, createdCustomer)
+	rest.Created(c, h.location, createdCustomer.ID, createdCustomer.Version, customerResponse(createdCustomer))
 }
 
 // GetCustomers godoc
@@ -48,42 +67,95 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 // @Description Retrieves all customers from the database
 // @Tags customers
 // @Produce json
-// @Success 200 {array} storage.Customer "List of customers"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param all query bool false "Return every row as a plain array instead of a page (deprecated)"
+// @Param include_deleted query bool false "Include soft-deleted customers"
+// @Success 200 {object} storage.Page[v1.CustomerResponse] "List of customers"
+// @Failure 400 {object} storage.ResponseError "Invalid pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:read"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/customers [get]
 func (h *CustomerHandler) GetCustomers(c *gin.Context) {
-	customers, err := storage.GetCustomers(h.db)
+	page, all, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	includeDeleted, err := rest.QueryBool(c, "include_deleted")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	customers, err := h.store.GetCustomers(c.Request.Context(), page, includeDeleted)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, customers)
+	if all {
+		c.JSON(http.StatusOK, rest.MapSlice(customers.Data, customerResponse))
+		return
+	}
+	c.JSON(http.StatusOK, rest.MapPage(customers, customerResponse))
 }
 
 // UpdateCustomer godoc
 // @Summary Update an existing customer
-// @Description Updates an existing customer in the database
+// @Description Replaces an existing customer. The ID is taken from the path; an ID in the body is ignored. The balance is read-only; it changes through top-ups and adjustments
 // @Tags customers
 // @Accept json
 // @Produce json
 // @Param id path int true "Customer ID"
-// @Param input body storage.Customer true "Customer information"
-// @Success 200 {object} storage.Customer "Updated customer"
+// @Param If-Match header string false "ETag of the customer as last read; the update fails with 412 if it changed since"
+// @Param input body v1.CustomerUpdateRequest true "Customer information"
+// @Success 200 {object} v1.CustomerResponse "Updated customer"
+// @Header 200 {string} ETag "Version of the updated customer"
 // @Failure 400 {object} storage.ResponseError "Invalid customer data"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:write"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 409 {object} storage.ResponseError "Customer was modified since the version in the body"
+// @Failure 412 {object} storage.ResponseError "Customer was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/customer/update/{id} [put]
 func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
-	var customer storage.Customer
-	if err := c.ShouldBindJSON(&customer); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	customerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(rest.InvalidID("customer"))
+		return
+	}
+	var request CustomerUpdateRequest
+	if err := rest.BindJSON(c, &request); err != nil {
+		c.Error(err)
+		return
+	}
+	customer := request.customer()
+	customer.ID = customerID
+	version, conditional, err := rest.IfMatch(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
-	updatedCustomer, err := storage.UpdateCustomer(h.db, customer)
+	if conditional {
+		customer.Version = version
+	}
+	updatedCustomer, err := h.store.UpdateCustomer(c.Request.Context(), customer)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(rest.ConditionalError(err, conditional, "customer"))
 		return
 	}
-	c.JSON(http.StatusOK, updatedCustomer)
+	rest.SetETag(c, updatedCustomer.Version)
+	c.JSON(http.StatusOK, customerResponse(updatedCustomer))
 }
 
 // DeleteCustomer godoc
@@ -91,23 +163,30 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 // @Description Soft deletes a customer from the database
 // @Tags customers
 // @Param id path int true "Customer ID"
-// @Success 200 {string} string "Customer deleted successfully"
+// @Success 200 {object} v1.CustomerResponse "Deleted customer"
 // @Failure 400 {object} storage.ResponseError "Invalid customer ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:delete"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/customer/delete/{id} [delete]
 func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
 	id := c.Param("id")
 	customerID, err := strconv.Atoi(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		c.Error(rest.InvalidID("customer"))
 		return
 	}
-	deletedCustomer, err := storage.DeleteCustomer(h.db, customerID)
+	deletedCustomer, err := h.store.DeleteCustomer(c.Request.Context(), customerID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, deletedCustomer)
+	c.JSON(http.StatusOK, customerResponse(deletedCustomer))
 }
 
 // GetCustomer godoc
@@ -115,23 +194,128 @@ func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
 // @Description Retrieves a single customer by its ID from the database
 // @Tags customers
 // @Param id path int true "Customer ID"
+// @Param include_deleted query bool false "Return the customer even if it is soft-deleted"
 // @Produce json
-// @Success 200 {object} storage.Customer "Customer details"
+// @Success 200 {object} v1.CustomerResponse "Customer details"
+// @Header 200 {string} ETag "Version of the customer, for If-Match"
 // @Failure 400 {object} storage.ResponseError "Invalid customer ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:read"
 // @Failure 404 {object} storage.ResponseError "Customer not found"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
-// @Router /v1/customer/{id} [get]
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
+// @Router /v1/customer/get/{id} [get]
 func (h *CustomerHandler) GetCustomer(c *gin.Context) {
 	id := c.Param("id")
 	customerID, err := strconv.Atoi(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		c.Error(rest.InvalidID("customer"))
 		return
 	}
-	customer, err := storage.GetCustomer(h.db, customerID)
+	includeDeleted, err := rest.QueryBool(c, "include_deleted")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	customer, err := h.store.GetCustomer(c.Request.Context(), customerID, includeDeleted)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	rest.SetETag(c, customer.Version)
+	c.JSON(http.StatusOK, customerResponse(customer))
+}
+
+// RestoreCustomer godoc
+// @Summary Restore a deleted customer
+// @Description Undoes a soft delete of a customer
+// @Tags customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Success 200 {object} v1.CustomerResponse "Restored customer"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:delete"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 409 {object} storage.ResponseError "Customer is not deleted"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
+// @Router /v1/customer/restore/{id} [post]
+func (h *CustomerHandler) RestoreCustomer(c *gin.Context) {
+	id := c.Param("id")
+	customerID, err := strconv.Atoi(id)
+	if err != nil {
+		c.Error(rest.InvalidID("customer"))
+		return
+	}
+	restoredCustomer, err := h.store.RestoreCustomer(c.Request.Context(), customerID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	rest.SetETag(c, restoredCustomer.Version)
+	c.JSON(http.StatusOK, customerResponse(restoredCustomer))
+}
+
+// PatchCustomer godoc
+// @Summary Partially update a customer
+// @Description Applies an RFC 7396 JSON merge patch. Only customer_name can be changed; members that are absent keep their value, and balance is read-only. Returns the updated customer as stored
+// @Tags customers
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param If-Match header string false "ETag of the customer as last read; the update fails with 412 if it changed since"
+// @Param patch body v1.CustomerUpdateRequest true "Members to change"
+// @Success 200 {object} v1.CustomerResponse "Updated customer"
+// @Header 200 {string} ETag "Version of the updated customer"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID or patch"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:write"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 409 {object} storage.ResponseError "Customer was modified since the version in the patch"
+// @Failure 412 {object} storage.ResponseError "Customer was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
+// @Router /v1/customer/update/{id} [patch]
+func (h *CustomerHandler) PatchCustomer(c *gin.Context) {
+	customerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(rest.InvalidID("customer"))
+		return
+	}
+	var request CustomerUpdateRequest
+	present, err := rest.BindMergePatch(c, &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	customerPatch := storage.CustomerPatch{Version: request.Version}
+	if present["customer_name"] {
+		customerPatch.Name = &request.Name
+	}
+	version, conditional, err := rest.IfMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if conditional {
+		customerPatch.Version = version
+	}
+	patchedCustomer, err := h.store.PatchCustomer(c.Request.Context(), customerID, customerPatch)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(rest.ConditionalError(err, conditional, "customer"))
 		return
 	}
-	c.JSON(http.StatusOK, customer)
+	rest.SetETag(c, patchedCustomer.Version)
+	c.JSON(http.StatusOK, customerResponse(patchedCustomer))
 }
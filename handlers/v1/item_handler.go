@@ -1,21 +1,30 @@
 package v1
 
 import (
-	"database/sql"
 	"net/http"
 	"strconv"
 
+	"lesson/handlers/rest"
 	"lesson/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ItemHandler struct {
-	db *sql.DB
+	store    storage.ItemStore
+	location string
 }
 
-func NewItemHandler(db *sql.DB) *ItemHandler {
-	return &ItemHandler{db: db}
+func NewItemHandler(store storage.ItemStore) *ItemHandler {
+	return &ItemHandler{store: store}
+}
+
+// WithLocation returns a copy of h that answers creates with a Location
+// header of path/{id}.
+func (h *ItemHandler) WithLocation(path string) *ItemHandler {
+	copied := *h
+	copied.location = path
+	return &copied
 }
 
 // GetItems godoc
@@ -23,16 +32,41 @@ func NewItemHandler(db *sql.DB) *ItemHandler {
 // @Description Retrieves all items from the database
 // @Tags items
 // @Produce json
-// @Success 200 {array} storage.Item "List of items"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param all query bool false "Return every row as a plain array instead of a page (deprecated)"
+// @Param include_deleted query bool false "Include soft-deleted items"
+// @Success 200 {object} storage.Page[v1.ItemResponse] "List of items"
+// @Failure 400 {object} storage.ResponseError "Invalid pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:read"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/items [get]
 func (h *ItemHandler) GetItems(c *gin.Context) {
-	items, err := storage.GetItems(h.db)
+	page, all, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	includeDeleted, err := rest.QueryBool(c, "include_deleted")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	items, err := h.store.GetItems(c.Request.Context(), page, includeDeleted)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, items)
+	if all {
+		c.JSON(http.StatusOK, rest.MapSlice(items.Data, itemResponse))
+		return
+	}
+	c.JSON(http.StatusOK, rest.MapPage(items, itemResponse))
 }
 
 // CreateItem godoc
@@ -41,48 +75,87 @@ func (h *ItemHandler) GetItems(c *gin.Context) {
 // @Tags items
 // @Accept json
 // @Produce json
-// @Param input body storage.Item true "Item information"
-// @Success 201 {object} storage.Item "Created item"
+// @Param input body v1.ItemRequest true "Item information"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v1.ItemResponse "Created item"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
 // @Failure 400 {object} storage.ResponseError "Invalid item data"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:write"
+// @Failure 409 {object} storage.ResponseError "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/item/create [post]
 func (h *ItemHandler) CreateItem(c *gin.Context) {
-	var item storage.Item
-	if err := c.ShouldBindJSON(&item); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var request ItemRequest
+	if err := rest.BindJSON(c, &request); err != nil {
+		c.Error(err)
 		return
 	}
-	createdItem, err := storage.CreateItem(h.db, item)
+	createdItem, err := h.store.CreateItem(c.Request.Context(), request.item())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusCreated, createdItem)
+	rest.Created(c, h.location, createdItem.ID, createdItem.Version, itemResponse(createdItem))
 }
 
+// UpdateItem godoc
 // @Summary Update an existing item
-// @Description Updates an existing item in the database
+// @Description Replaces an existing item. The ID is taken from the path; an ID in the body is ignored
 // @Tags items
 // @Accept json
 // @Produce json
 // @Param id path int true "Item ID"
-// @Param input body storage.Item true "Item information"
-// @Success 200 {object} storage.Item "Updated item"
+// @Param If-Match header string false "ETag of the item as last read; the update fails with 412 if it changed since"
+// @Param input body v1.ItemUpdateRequest true "Item information"
+// @Success 200 {object} v1.ItemResponse "Updated item"
+// @Header 200 {string} ETag "Version of the updated item"
 // @Failure 400 {object} storage.ResponseError "Invalid item data"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:write"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 409 {object} storage.ResponseError "Item was modified since the version in the body"
+// @Failure 412 {object} storage.ResponseError "Item was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/item/update/{id} [put]
 func (h *ItemHandler) UpdateItem(c *gin.Context) {
-	var item storage.Item
-	if err := c.ShouldBindJSON(&item); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(rest.InvalidID("item"))
+		return
+	}
+	var request ItemUpdateRequest
+	if err := rest.BindJSON(c, &request); err != nil {
+		c.Error(err)
+		return
+	}
+	item := request.item()
+	item.ID = itemID
+	version, conditional, err := rest.IfMatch(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
-	updatedItem, err := storage.UpdateItem(h.db, item)
+	if conditional {
+		item.Version = version
+	}
+	updatedItem, err := h.store.UpdateItem(c.Request.Context(), item)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(rest.ConditionalError(err, conditional, "item"))
 		return
 	}
-	c.JSON(http.StatusOK, updatedItem)
+	rest.SetETag(c, updatedItem.Version)
+	c.JSON(http.StatusOK, itemResponse(updatedItem))
 }
 
 // DeleteItem godoc
@@ -90,23 +163,30 @@ func (h *ItemHandler) UpdateItem(c *gin.Context) {
 // @Description Soft deletes an item from the database
 // @Tags items
 // @Param id path int true "Item ID"
-// @Success 200 {string} string "Item deleted successfully"
+// @Success 200 {object} v1.ItemResponse "Deleted item"
 // @Failure 400 {object} storage.ResponseError "Invalid item ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:delete"
+// @Failure 404 {object} storage.ResponseError "Item not found"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/item/delete/{id} [delete]
 func (h *ItemHandler) DeleteItem(c *gin.Context) {
 	id := c.Param("id")
 	itemID, err := strconv.Atoi(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		c.Error(rest.InvalidID("item"))
 		return
 	}
-	deletedItem, err := storage.DeleteItem(h.db, itemID)
+	deletedItem, err := h.store.DeleteItem(c.Request.Context(), itemID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, deletedItem)
+	c.JSON(http.StatusOK, itemResponse(deletedItem))
 }
 
 // GetItem godoc
@@ -114,23 +194,140 @@ func (h *ItemHandler) DeleteItem(c *gin.Context) {
 // @Description Retrieves a single item by its ID from the database
 // @Tags items
 // @Param id path int true "Item ID"
+// @Param include_deleted query bool false "Return the item even if it is soft-deleted"
 // @Produce json
-// @Success 200 {object} storage.Item "Item details"
+// @Success 200 {object} v1.ItemResponse "Item details"
+// @Header 200 {string} ETag "Version of the item, for If-Match"
 // @Failure 400 {object} storage.ResponseError "Invalid item ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:read"
 // @Failure 404 {object} storage.ResponseError "Item not found"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
-// @Router /v1/item/{id} [get]
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
+// @Router /v1/item/get/{id} [get]
 func (h *ItemHandler) GetItem(c *gin.Context) {
 	id := c.Param("id")
 	itemID, err := strconv.Atoi(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		c.Error(rest.InvalidID("item"))
+		return
+	}
+	includeDeleted, err := rest.QueryBool(c, "include_deleted")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	item, err := h.store.GetItem(c.Request.Context(), itemID, includeDeleted)
+	if err != nil {
+		c.Error(err)
 		return
 	}
-	item, err := storage.GetItem(h.db, itemID)
+	rest.SetETag(c, item.Version)
+	c.JSON(http.StatusOK, itemResponse(item))
+}
+
+// RestoreItem godoc
+// @Summary Restore a deleted item
+// @Description Undoes a soft delete of an item
+// @Tags items
+// @Produce json
+// @Param id path int true "Item ID"
+// @Success 200 {object} v1.ItemResponse "Restored item"
+// @Failure 400 {object} storage.ResponseError "Invalid item ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:delete"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 409 {object} storage.ResponseError "Item is not deleted"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
+// @Router /v1/item/restore/{id} [post]
+func (h *ItemHandler) RestoreItem(c *gin.Context) {
+	id := c.Param("id")
+	itemID, err := strconv.Atoi(id)
+	if err != nil {
+		c.Error(rest.InvalidID("item"))
+		return
+	}
+	restoredItem, err := h.store.RestoreItem(c.Request.Context(), itemID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	rest.SetETag(c, restoredItem.Version)
+	c.JSON(http.StatusOK, itemResponse(restoredItem))
+}
+
+// PatchItem godoc
+// @Summary Partially update a item
+// @Description Applies an RFC 7396 JSON merge patch. Only item_name, cost, price, sort, allow_backorder can be changed; members that are absent keep their value. Returns the updated item as stored
+// @Tags items
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param If-Match header string false "ETag of the item as last read; the update fails with 412 if it changed since"
+// @Param patch body v1.ItemUpdateRequest true "Members to change"
+// @Success 200 {object} v1.ItemResponse "Updated item"
+// @Header 200 {string} ETag "Version of the updated item"
+// @Failure 400 {object} storage.ResponseError "Invalid item ID or patch"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:write"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 409 {object} storage.ResponseError "Item was modified since the version in the patch"
+// @Failure 412 {object} storage.ResponseError "Item was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
+// @Router /v1/item/update/{id} [patch]
+func (h *ItemHandler) PatchItem(c *gin.Context) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(rest.InvalidID("item"))
+		return
+	}
+	var request ItemUpdateRequest
+	present, err := rest.BindMergePatch(c, &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	itemPatch := storage.ItemPatch{Version: request.Version}
+	if present["item_name"] {
+		itemPatch.Name = &request.Name
+	}
+	if present["cost"] {
+		itemPatch.Cost = &request.Cost
+	}
+	if present["price"] {
+		itemPatch.Price = &request.Price
+	}
+	if present["sort"] {
+		itemPatch.Sort = &request.Sort
+	}
+	if present["allow_backorder"] {
+		itemPatch.AllowBackorder = &request.AllowBackorder
+	}
+	version, conditional, err := rest.IfMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if conditional {
+		itemPatch.Version = version
+	}
+	patchedItem, err := h.store.PatchItem(c.Request.Context(), itemID, itemPatch)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(rest.ConditionalError(err, conditional, "item"))
 		return
 	}
-	c.JSON(http.StatusOK, item)
+	rest.SetETag(c, patchedItem.Version)
+	c.JSON(http.StatusOK, itemResponse(patchedItem))
 }
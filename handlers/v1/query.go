@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"strconv"
+
+	"lesson/handlers/middleware"
+	"lesson/handlers/rest"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageRequest reads the limit and cursor parameters of list endpoints. all
+// reports whether the client opted into the legacy unpaginated response by
+// passing all=true, which only routes behind middleware.UnpaginatedLists
+// honour.
+func pageRequest(c *gin.Context) (page storage.PageRequest, all bool, err error) {
+	if c.GetBool(middleware.UnpaginatedListsKey) && c.Query("all") != "" {
+		all, err = strconv.ParseBool(c.Query("all"))
+		if err != nil {
+			return page, false, rest.InvalidParam("all", "must be a boolean")
+		}
+		if all {
+			return page, true, nil
+		}
+	}
+	page, err = rest.PageRequest(c)
+	return page, false, err
+}
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"lesson/handlers/rest"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomerRequest is the body of a customer create request. A non-zero
+// balance is posted as the customer's first top-up.
+type CustomerRequest struct {
+	Name    string        `json:"customer_name" binding:"required,max=255" example:"John Doe"`
+	Balance storage.Money `json:"balance" binding:"gte=0,lt=1000000000000" swaggertype:"string" example:"1000.00"`
+}
+
+// CustomerUpdateRequest is the body of customer update and patch requests. A
+// non-zero Version makes the update conditional on the stored version. The
+// balance cannot be written; it changes through top-ups and adjustments.
+type CustomerUpdateRequest struct {
+	Name    string `json:"customer_name" binding:"required,max=255" example:"John Doe"`
+	Version int    `json:"version" binding:"gte=0" example:"1"`
+}
+
+func (r CustomerRequest) customer() storage.Customer {
+	return storage.Customer{Name: r.Name, Balance: r.Balance}
+}
+
+func (r CustomerUpdateRequest) customer() storage.Customer {
+	return storage.Customer{Name: r.Name}
+}
+
+// ItemRequest is the body of an item create request. Stock is not part of
+// it; it only changes through stock movements.
+type ItemRequest struct {
+	Name           string        `json:"item_name" binding:"required,max=255" example:"Laptop"`
+	Cost           storage.Money `json:"cost" binding:"gte=0,lt=1000000000000" swaggertype:"string" example:"950.00"`
+	Price          storage.Money `json:"price" binding:"gte=0,lt=1000000000000" swaggertype:"string" example:"1200.00"`
+	Sort           int           `json:"sort" example:"10"`
+	AllowBackorder bool          `json:"allow_backorder" example:"false"`
+}
+
+// ItemUpdateRequest is the body of item update and patch requests. A
+// non-zero Version makes the update conditional on the stored version.
+type ItemUpdateRequest struct {
+	ItemRequest
+	Version int `json:"version" binding:"gte=0" example:"1"`
+}
+
+func (r ItemRequest) item() storage.Item {
+	return storage.Item{Name: r.Name, Cost: r.Cost, Price: r.Price, Sort: r.Sort, AllowBackorder: r.AllowBackorder}
+}
+
+// TransactionRequest is the body of a purchase. The amount is computed from
+// the item's price.
+type TransactionRequest struct {
+	CustomerID int `json:"customer_id" binding:"required,gte=1" example:"1"`
+	ItemID     int `json:"item_id" binding:"required,gte=1" example:"1"`
+	Qty        int `json:"qty" binding:"required,gte=1" example:"2"`
+}
+
+// legacyTransactionRequest is TransactionRequest with the member names v1
+// used before the API moved to snake_case.
+type legacyTransactionRequest struct {
+	CustomerID int `json:"CustomerID" binding:"required,gte=1"`
+	ItemID     int `json:"ItemID" binding:"required,gte=1"`
+	Qty        int `json:"Qty" binding:"required,gte=1"`
+}
+
+func (r TransactionRequest) transaction() storage.Transaction {
+	return storage.Transaction{CustomerID: r.CustomerID, ItemID: r.ItemID, Qty: r.Qty}
+}
+
+// TransactionUpdateRequest is the body of transaction update and patch
+// requests. The amount follows from the quantity and the price of the sale,
+// so it is read-only like on create. A non-zero Version makes the update
+// conditional on the stored version.
+type TransactionUpdateRequest struct {
+	Qty     int `json:"qty" binding:"required,gte=1" example:"2"`
+	Version int `json:"version" binding:"gte=0" example:"1"`
+}
+
+// legacyTransactionUpdateRequest is TransactionUpdateRequest with the member
+// names v1 used before the API moved to snake_case.
+type legacyTransactionUpdateRequest struct {
+	Qty     int `json:"Qty" binding:"required,gte=1"`
+	Version int `json:"Version" binding:"gte=0"`
+}
+
+// legacyTransactionMembers maps the legacy member names to the current ones.
+var legacyTransactionMembers = map[string]string{
+	"CustomerID": "customer_id",
+	"ItemID":     "item_id",
+	"Qty":        "qty",
+	"Version":    "version",
+}
+
+func (r TransactionUpdateRequest) transaction() storage.Transaction {
+	return storage.Transaction{Qty: r.Qty, Version: r.Version}
+}
+
+// bindTransactionRequest decodes a purchase in the member names the request
+// uses.
+func bindTransactionRequest(c *gin.Context) (TransactionRequest, error) {
+	if legacyJSON(c) {
+		var request legacyTransactionRequest
+		err := rest.BindJSON(c, &request)
+		return TransactionRequest(request), err
+	}
+	var request TransactionRequest
+	err := rest.BindJSON(c, &request)
+	return request, err
+}
+
+// bindTransactionUpdate decodes a transaction update, or a merge patch when
+// partial is set, in the member names the request uses. present holds the
+// current names of the members a patch sets.
+func bindTransactionUpdate(c *gin.Context, partial bool) (request TransactionUpdateRequest, present map[string]bool, err error) {
+	if !legacyJSON(c) {
+		present, err = rest.DecodeRequest(c, &request, partial)
+		return request, present, err
+	}
+	var legacy legacyTransactionUpdateRequest
+	legacyPresent, err := rest.DecodeRequest(c, &legacy, partial)
+	present = make(map[string]bool, len(legacyPresent))
+	for name := range legacyPresent {
+		present[legacyTransactionMembers[name]] = true
+	}
+	return TransactionUpdateRequest(legacy), present, err
+}
@@ -0,0 +1,197 @@
+package v1
+
+import (
+	"time"
+
+	"lesson/handlers/middleware"
+	"lesson/handlers/rest"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomerResponse is a customer as the API returns it.
+type CustomerResponse struct {
+	ID        int           `json:"id" example:"1"`
+	Name      string        `json:"customer_name" example:"John Doe"`
+	Balance   storage.Money `json:"balance" swaggertype:"string" example:"1000.00"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	DeletedAt *time.Time    `json:"deleted_at" extensions:"x-nullable"`
+	Version   int           `json:"version" example:"1"`
+}
+
+func customerResponse(customer storage.Customer) CustomerResponse {
+	return CustomerResponse{
+		ID:        customer.ID,
+		Name:      customer.Name,
+		Balance:   customer.Balance,
+		CreatedAt: customer.CreatedAt,
+		UpdatedAt: customer.UpdatedAt,
+		DeletedAt: customer.DeletedAt,
+		Version:   customer.Version,
+	}
+}
+
+// ItemResponse is an item as the API returns it.
+type ItemResponse struct {
+	ID             int           `json:"id" example:"1"`
+	Name           string        `json:"item_name" example:"Laptop"`
+	Cost           storage.Money `json:"cost" swaggertype:"string" example:"950.00"`
+	Price          storage.Money `json:"price" swaggertype:"string" example:"1200.00"`
+	Sort           int           `json:"sort" example:"10"`
+	Stock          int           `json:"stock" example:"25"`
+	AllowBackorder bool          `json:"allow_backorder" example:"false"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	DeletedAt      *time.Time    `json:"deleted_at" extensions:"x-nullable"`
+	Version        int           `json:"version" example:"1"`
+}
+
+func itemResponse(item storage.Item) ItemResponse {
+	return ItemResponse{
+		ID:             item.ID,
+		Name:           item.Name,
+		Cost:           item.Cost,
+		Price:          item.Price,
+		Sort:           item.Sort,
+		Stock:          item.Stock,
+		AllowBackorder: item.AllowBackorder,
+		CreatedAt:      item.CreatedAt,
+		UpdatedAt:      item.UpdatedAt,
+		DeletedAt:      item.DeletedAt,
+		Version:        item.Version,
+	}
+}
+
+// TransactionResponse is a transaction as the API returns it. Sales name
+// the order they are a line of in order_id. Voids and refunds name the sale
+// they reverse in original_transaction_id and carry a negative qty and
+// amount.
+type TransactionResponse struct {
+	ID                    int           `json:"id" example:"1"`
+	CustomerID            int           `json:"customer_id" example:"1"`
+	ItemID                int           `json:"item_id" example:"1"`
+	Qty                   int           `json:"qty" example:"2"`
+	Amount                storage.Money `json:"amount" swaggertype:"string" example:"2400.00"`
+	Type                  string        `json:"type" enums:"sale,void,refund" example:"sale"`
+	OriginalTransactionID *int          `json:"original_transaction_id" extensions:"x-nullable" example:"1"`
+	Reason                string        `json:"reason" example:"wrong size"`
+	OrderID               *int          `json:"order_id" extensions:"x-nullable" example:"1"`
+	CreatedAt             time.Time     `json:"created_at"`
+	UpdatedAt             time.Time     `json:"updated_at"`
+	DeletedAt             *time.Time    `json:"deleted_at" extensions:"x-nullable"`
+	Version               int           `json:"version" example:"1"`
+}
+
+// legacyTransactionResponse is TransactionResponse with the member names v1
+// used before the API moved to snake_case, for clients that have not
+// migrated yet.
+type legacyTransactionResponse struct {
+	ID                    int           `json:"ID"`
+	CustomerID            int           `json:"CustomerID"`
+	ItemID                int           `json:"ItemID"`
+	Qty                   int           `json:"Qty"`
+	Amount                storage.Money `json:"Amount"`
+	Type                  string        `json:"Type"`
+	OriginalTransactionID *int          `json:"OriginalTransactionID"`
+	Reason                string        `json:"Reason"`
+	OrderID               *int          `json:"OrderID"`
+	CreatedAt             time.Time     `json:"CreatedAt"`
+	UpdatedAt             time.Time     `json:"UpdatedAt"`
+	DeletedAt             *time.Time    `json:"DeletedAt"`
+	Version               int           `json:"Version"`
+}
+
+// NewTransactionResponse converts a transaction for the responses of both API
+// versions, which share TransactionResponse.
+func NewTransactionResponse(transaction storage.Transaction) TransactionResponse {
+	return TransactionResponse{
+		ID:                    transaction.ID,
+		CustomerID:            transaction.CustomerID,
+		ItemID:                transaction.ItemID,
+		Qty:                   transaction.Qty,
+		Amount:                transaction.Amount,
+		Type:                  transaction.Type,
+		OriginalTransactionID: transaction.OriginalTransactionID,
+		Reason:                transaction.Reason,
+		OrderID:               transaction.OrderID,
+		CreatedAt:             transaction.CreatedAt,
+		UpdatedAt:             transaction.UpdatedAt,
+		DeletedAt:             transaction.DeletedAt,
+		Version:               transaction.Version,
+	}
+}
+
+// TransactionViewResponse is a transaction joined with its customer and item
+// as the API returns it.
+type TransactionViewResponse struct {
+	ID                    int           `json:"id" example:"1"`
+	CustomerID            int           `json:"customer_id" example:"1"`
+	CustomerName          string        `json:"customer_name" example:"John Doe"`
+	ItemID                int           `json:"item_id" example:"1"`
+	ItemName              string        `json:"item_name" example:"Laptop"`
+	Qty                   int           `json:"qty" example:"2"`
+	Price                 storage.Money `json:"price" swaggertype:"string" example:"1200.00"`
+	Amount                storage.Money `json:"amount" swaggertype:"string" example:"2400.00"`
+	Type                  string        `json:"type" enums:"sale,void,refund" example:"sale"`
+	OriginalTransactionID *int          `json:"original_transaction_id" extensions:"x-nullable" example:"1"`
+	CreatedAt             time.Time     `json:"created_at"`
+	UpdatedAt             time.Time     `json:"updated_at"`
+	DeletedAt             *time.Time    `json:"deleted_at" extensions:"x-nullable"`
+}
+
+func transactionViewResponse(view storage.TransactionView) TransactionViewResponse {
+	return TransactionViewResponse{
+		ID:                    view.ID,
+		CustomerID:            view.CustomerID,
+		CustomerName:          view.CustomerName,
+		ItemID:                view.ItemID,
+		ItemName:              view.ItemName,
+		Qty:                   view.Qty,
+		Price:                 view.Price,
+		Amount:                view.Amount,
+		Type:                  view.Type,
+		OriginalTransactionID: view.OriginalTransactionID,
+		CreatedAt:             view.CreatedAt,
+		UpdatedAt:             view.UpdatedAt,
+		DeletedAt:             view.DeletedAt,
+	}
+}
+
+// legacyJSON reports whether the request uses the legacy transaction member
+// names; see middleware.LegacyJSON.
+func legacyJSON(c *gin.Context) bool {
+	return c.GetBool(middleware.LegacyJSONKey)
+}
+
+// transactionBody renders a transaction with the member names the request
+// asked for.
+func transactionBody(c *gin.Context, transaction storage.Transaction) interface{} {
+	if legacyJSON(c) {
+		return legacyTransaction(transaction)
+	}
+	return NewTransactionResponse(transaction)
+}
+
+func legacyTransaction(transaction storage.Transaction) legacyTransactionResponse {
+	return legacyTransactionResponse(NewTransactionResponse(transaction))
+}
+
+// transactionsBody renders a list of transactions with the member names the
+// request asked for.
+func transactionsBody(c *gin.Context, transactions []storage.Transaction) interface{} {
+	if legacyJSON(c) {
+		return rest.MapSlice(transactions, legacyTransaction)
+	}
+	return rest.MapSlice(transactions, NewTransactionResponse)
+}
+
+// transactionPageBody renders a page of transactions with the member names
+// the request asked for.
+func transactionPageBody(c *gin.Context, page storage.Page[storage.Transaction]) interface{} {
+	if legacyJSON(c) {
+		return rest.MapPage(page, legacyTransaction)
+	}
+	return rest.MapPage(page, NewTransactionResponse)
+}
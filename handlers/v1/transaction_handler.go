@@ -1,96 +1,147 @@
 package v1
 
 import (
-	"database/sql"
 	"net/http"
 	"strconv"
 
+	"lesson/handlers/rest"
 	"lesson/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
 type TransactionHandler struct {
-	db *sql.DB
+	store    storage.TransactionStore
+	location string
 }
 
-func NewTransactionHandler(db *sql.DB) *TransactionHandler {
-	return &TransactionHandler{db: db}
+func NewTransactionHandler(store storage.TransactionStore) *TransactionHandler {
+	return &TransactionHandler{store: store}
+}
+
+// WithLocation returns a copy of h that answers creates with a Location
+// header of path/{id}.
+func (h *TransactionHandler) WithLocation(path string) *TransactionHandler {
+	copied := *h
+	copied.location = path
+	return &copied
 }
 
 // CreateTransaction godoc
 // @Summary Create a new transaction
-// @Description Creates a new transaction
+// @Description Records a purchase as an order of one line: the amount is computed from the item's current price and debited from the customer's balance atomically. Use /v2/orders to buy several items at once
 // @Tags transactions
 // @Accept json
 // @Produce json
-// @Param user body storage.Transaction true "Transaction information"
-// @Success 200 {object} storage.Transaction "Created transaction"
+// @Param input body v1.TransactionRequest true "Transaction information"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v1.TransactionResponse "Created transaction"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
 // @Failure 400 {object} storage.ResponseError "Invalid transaction data"
-// @Failure 401 {object} storage.ResponseError "Unauthorized"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:create"
+// @Failure 409 {object} storage.ResponseError "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed, unknown customer or item, or insufficient balance"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/transaction/create [post]
 func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
-	var transaction storage.Transaction
-	if err := c.BindJSON(&transaction); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	request, err := bindTransactionRequest(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
-	createdTransaction, err := storage.CreateTransaction(h.db, transaction)
+	createdTransaction, err := h.store.CreateTransaction(c.Request.Context(), request.transaction())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(referenceError(c, err))
 		return
 	}
-	c.JSON(http.StatusCreated, createdTransaction)
+	rest.Created(c, h.location, createdTransaction.ID, createdTransaction.Version, transactionBody(c, createdTransaction))
 }
 
 // UpdateTransaction godoc
 // @Summary Update an existing transaction
-// @Description Updates an existing transaction in the database
+// @Description Replaces the qty of an existing transaction; the amount is recomputed at the price of the sale. The ID is taken from the path; an ID in the body is ignored
 // @Tags transactions
 // @Accept json
 // @Produce json
 // @Param id path int true "Transaction ID"
-// @Param input body storage.Transaction true "Transaction information"
-// @Success 200 {object} storage.Transaction "Updated transaction"
+// @Param If-Match header string false "ETag of the transaction as last read; the update fails with 412 if it changed since"
+// @Param input body v1.TransactionUpdateRequest true "Transaction information"
+// @Success 200 {object} v1.TransactionResponse "Updated transaction"
+// @Header 200 {string} ETag "Version of the updated transaction"
 // @Failure 400 {object} storage.ResponseError "Invalid transaction data"
-// @Failure 401 {object} storage.ResponseError "Unauthorized"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:write"
 // @Failure 404 {object} storage.ResponseError "Transaction not found"
+// @Failure 409 {object} storage.ResponseError "Transaction was modified since the version in the body, or was voided or refunded"
+// @Failure 412 {object} storage.ResponseError "Transaction was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed, the transaction is not a sale, or the customer's balance or the item's stock does not cover a larger qty"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/transaction/update/{id} [put]
 func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
-	var transaction storage.Transaction
-	if err := c.BindJSON(&transaction); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	transactionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(rest.InvalidID("transaction"))
 		return
 	}
-	updatedTransaction, err := storage.UpdateTransaction(h.db, transaction)
+	request, _, err := bindTransactionUpdate(c, false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	transaction := request.transaction()
+	transaction.ID = transactionID
+	version, conditional, err := rest.IfMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if conditional {
+		transaction.Version = version
+	}
+	updatedTransaction, err := h.store.UpdateTransaction(c.Request.Context(), transaction)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(rest.ConditionalError(err, conditional, "transaction"))
 		return
 	}
-	c.JSON(http.StatusOK, updatedTransaction)
+	rest.SetETag(c, updatedTransaction.Version)
+	c.JSON(http.StatusOK, transactionBody(c, updatedTransaction))
 }
 
 // DeleteTransaction godoc
 // @Summary Delete a transaction
-// @Description Soft deletes a transaction from the database
+// @Description Soft deletes a transaction from the database. The customer's balance and the item's stock are not touched; void or refund a sale to reverse it
 // @Tags transactions
 // @Param id path int true "Transaction ID"
 // @Success 200 {string} string "Transaction deleted successfully"
 // @Failure 400 {object} storage.ResponseError "Invalid transaction ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:void"
+// @Failure 404 {object} storage.ResponseError "Transaction not found"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/transaction/delete/{id} [delete]
 func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
 	id := c.Param("id")
 	transactionID, err := strconv.Atoi(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		c.Error(rest.InvalidID("transaction"))
 		return
 	}
-	err = storage.DeleteTransaction(h.db, transactionID)
+	err = h.store.DeleteTransaction(c.Request.Context(), transactionID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Transaction deleted successfully"})
@@ -101,25 +152,39 @@ func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
 // @Description Retrieves a single transaction by its ID from the database
 // @Tags transactions
 // @Param id path int true "Transaction ID"
+// @Param include_deleted query bool false "Return the transaction even if it is soft-deleted"
 // @Produce json
-// @Success 200 {object} storage.Transaction "Transaction details"
+// @Success 200 {object} v1.TransactionResponse "Transaction details"
+// @Header 200 {string} ETag "Version of the transaction, for If-Match"
 // @Failure 400 {object} storage.ResponseError "Invalid transaction ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
 // @Failure 404 {object} storage.ResponseError "Transaction not found"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
-// @Router /v1/transaction/{id} [get]
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
+// @Router /v1/transaction/get/{id} [get]
 func (h *TransactionHandler) GetTransaction(c *gin.Context) {
 	id := c.Param("id")
 	transactionID, err := strconv.Atoi(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		c.Error(rest.InvalidID("transaction"))
 		return
 	}
-	transaction, err := storage.GetTransaction(h.db, transactionID)
+	includeDeleted, err := rest.QueryBool(c, "include_deleted")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, transaction)
+	transaction, err := h.store.GetTransaction(c.Request.Context(), transactionID, includeDeleted)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	rest.SetETag(c, transaction.Version)
+	c.JSON(http.StatusOK, transactionBody(c, transaction))
 }
 
 // GetTransactionDetailsWithCustomerAndItem godoc
@@ -127,16 +192,35 @@ func (h *TransactionHandler) GetTransaction(c *gin.Context) {
 // @Description Retrieves transaction details with customer and item information using INNER JOIN
 // @Tags transactions
 // @Produce json
-// @Success 200 {array} storage.TransactionView "List of transactions with customer and item details"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param all query bool false "Return every row as a plain array instead of a page (deprecated)"
+// @Success 200 {object} storage.Page[v1.TransactionViewResponse] "List of transactions with customer and item details"
+// @Failure 400 {object} storage.ResponseError "Invalid pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/transaction/details [get]
 func (h *TransactionHandler) GetTransactionDetailsWithCustomerAndItem(c *gin.Context) {
-	transactions, err := storage.GetTransactionDetailsWithCustomerAndItem(h.db)
+	page, all, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	transactions, err := h.store.GetTransactionDetailsWithCustomerAndItem(c.Request.Context(), page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, transactions)
+	if all {
+		c.JSON(http.StatusOK, rest.MapSlice(transactions.Data, transactionViewResponse))
+		return
+	}
+	c.JSON(http.StatusOK, rest.MapPage(transactions, transactionViewResponse))
 }
 
 // GetTransactions godoc
@@ -144,49 +228,223 @@ func (h *TransactionHandler) GetTransactionDetailsWithCustomerAndItem(c *gin.Con
 // @Description Retrieves all transactions from the database
 // @Tags transactions
 // @Produce json
-// @Success 200 {array} storage.Transaction "List of transactions"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param all query bool false "Return every row as a plain array instead of a page (deprecated)"
+// @Param include_deleted query bool false "Include soft-deleted transactions"
+// @Success 200 {object} storage.Page[v1.TransactionResponse] "List of transactions"
+// @Failure 400 {object} storage.ResponseError "Invalid pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/transactions [get]
 func (h *TransactionHandler) GetTransactions(c *gin.Context) {
-	transactions, err := storage.GetTransactions(h.db)
+	page, all, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	includeDeleted, err := rest.QueryBool(c, "include_deleted")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	transactions, err := h.store.GetTransactions(c.Request.Context(), page, includeDeleted)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, transactions)
+	if all {
+		c.JSON(http.StatusOK, transactionsBody(c, transactions.Data))
+		return
+	}
+	c.JSON(http.StatusOK, transactionPageBody(c, transactions))
 }
 
 // FilterTransactions godoc
 // @Summary Filter transactions
-// @Description Filters transactions based on parameters like ID, customer name, and item name
+// @Description Filters transactions by ID, customer and item, creation date, amount and quantity ranges, with sorting
 // @Tags transactions
 // @Produce json
 // @Param id query int false "Transaction ID"
-// @Param customer_name query string false "Customer name"
-// @Param item_name query string false "Item name"
-// @Success 200 {array} storage.TransactionView "List of filtered transactions"
+// @Param customer_id query []int false "Customer IDs, comma separated or repeated" collectionFormat(csv)
+// @Param item_id query []int false "Item IDs, comma separated or repeated" collectionFormat(csv)
+// @Param customer_name query string false "Case-insensitive partial customer name"
+// @Param item_name query string false "Case-insensitive partial item name"
+// @Param created_from query string false "Inclusive lower bound on created_at (RFC 3339 or YYYY-MM-DD)"
+// @Param created_to query string false "Exclusive upper bound on created_at (RFC 3339 or YYYY-MM-DD)"
+// @Param min_amount query string false "Minimum amount"
+// @Param max_amount query string false "Maximum amount"
+// @Param min_qty query int false "Minimum quantity"
+// @Param max_qty query int false "Maximum quantity"
+// @Param sort query string false "Sort column" Enums(id, customer_name, item_name, qty, price, amount, created_at)
+// @Param order query string false "Sort order" Enums(asc, desc)
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param all query bool false "Return every row as a plain array instead of a page (deprecated)"
+// @Success 200 {object} storage.Page[v1.TransactionViewResponse] "Page of filtered transactions"
 // @Failure 400 {object} storage.ResponseError "Invalid parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
 // @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
 // @Router /v1/transaction/filter [get]
 func (h *TransactionHandler) FilterTransactions(c *gin.Context) {
-	idStr := c.Query("id")
-	customerName := c.Query("customer_name")
-	itemName := c.Query("item_name")
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	page, all, err := pageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	transactions, err := h.store.FilterTransactions(c.Request.Context(), filter, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if all {
+		c.JSON(http.StatusOK, rest.MapSlice(transactions.Data, transactionViewResponse))
+		return
+	}
+	c.JSON(http.StatusOK, rest.MapPage(transactions, transactionViewResponse))
+}
+
+func parseTransactionFilter(c *gin.Context) (storage.TransactionFilter, error) {
+	filter := storage.TransactionFilter{
+		CustomerName: c.Query("customer_name"),
+		ItemName:     c.Query("item_name"),
+		Sort:         c.Query("sort"),
+		Order:        c.Query("order"),
+	}
 
-	var id int
 	var err error
-	if idStr != "" {
-		id, err = strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
-			return
-		}
+	if filter.ID, err = rest.QueryInt(c, "id"); err != nil {
+		return filter, err
+	}
+	if filter.CustomerIDs, err = rest.QueryIntList(c, "customer_id"); err != nil {
+		return filter, err
+	}
+	if filter.ItemIDs, err = rest.QueryIntList(c, "item_id"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, err = rest.QueryTime(c, "created_from", false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = rest.QueryTime(c, "created_to", true); err != nil {
+		return filter, err
+	}
+	if filter.MinAmount, err = rest.QueryMoneyPtr(c, "min_amount"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAmount, err = rest.QueryMoneyPtr(c, "max_amount"); err != nil {
+		return filter, err
+	}
+	if filter.MinQty, err = rest.QueryIntPtr(c, "min_qty"); err != nil {
+		return filter, err
+	}
+	if filter.MaxQty, err = rest.QueryIntPtr(c, "max_qty"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// RestoreTransaction godoc
+// @Summary Restore a deleted transaction
+// @Description Undoes a soft delete of a transaction
+// @Tags transactions
+// @Produce json
+// @Param id path int true "Transaction ID"
+// @Success 200 {object} v1.TransactionResponse "Restored transaction"
+// @Failure 400 {object} storage.ResponseError "Invalid transaction ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:void"
+// @Failure 404 {object} storage.ResponseError "Transaction not found"
+// @Failure 409 {object} storage.ResponseError "Transaction is not deleted"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
+// @Router /v1/transaction/restore/{id} [post]
+func (h *TransactionHandler) RestoreTransaction(c *gin.Context) {
+	id := c.Param("id")
+	transactionID, err := strconv.Atoi(id)
+	if err != nil {
+		c.Error(rest.InvalidID("transaction"))
+		return
+	}
+	restoredTransaction, err := h.store.RestoreTransaction(c.Request.Context(), transactionID)
+	if err != nil {
+		c.Error(err)
+		return
 	}
+	rest.SetETag(c, restoredTransaction.Version)
+	c.JSON(http.StatusOK, transactionBody(c, restoredTransaction))
+}
 
-	transactions, err := storage.FilterTransactions(h.db, id, customerName, itemName)
+// PatchTransaction godoc
+// @Summary Partially update a transaction
+// @Description Applies an RFC 7396 JSON merge patch. Only qty can be changed; the amount follows from it at the price of the sale. Members that are absent keep their value. Returns the updated transaction as stored
+// @Tags transactions
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Param id path int true "Transaction ID"
+// @Param If-Match header string false "ETag of the transaction as last read; the update fails with 412 if it changed since"
+// @Param patch body v1.TransactionUpdateRequest true "Members to change"
+// @Success 200 {object} v1.TransactionResponse "Updated transaction"
+// @Header 200 {string} ETag "Version of the updated transaction"
+// @Failure 400 {object} storage.ResponseError "Invalid transaction ID or patch"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:write"
+// @Failure 404 {object} storage.ResponseError "Transaction not found"
+// @Failure 409 {object} storage.ResponseError "Transaction was modified since the version in the patch, or was voided or refunded"
+// @Failure 412 {object} storage.ResponseError "Transaction was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed, the transaction is not a sale, or the customer's balance or the item's stock does not cover a larger qty"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Deprecated
+// @Router /v1/transaction/update/{id} [patch]
+func (h *TransactionHandler) PatchTransaction(c *gin.Context) {
+	transactionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(rest.InvalidID("transaction"))
+		return
+	}
+	request, present, err := bindTransactionUpdate(c, true)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	transactionPatch := storage.TransactionPatch{Version: request.Version}
+	if present["qty"] {
+		transactionPatch.Qty = &request.Qty
+	}
+	version, conditional, err := rest.IfMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if conditional {
+		transactionPatch.Version = version
+	}
+	patchedTransaction, err := h.store.PatchTransaction(c.Request.Context(), transactionID, transactionPatch)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(rest.ConditionalError(err, conditional, "transaction"))
 		return
 	}
-	c.JSON(http.StatusOK, transactions)
+	rest.SetETag(c, patchedTransaction.Version)
+	c.JSON(http.StatusOK, transactionBody(c, patchedTransaction))
 }
@@ -0,0 +1,24 @@
+package v1
+
+import (
+	"lesson/handlers/rest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// referenceError reports a purchase of a customer or item that does not exist
+// as a validation error of the member that names it, in the member names the
+// request uses.
+func referenceError(c *gin.Context, err error) error {
+	if !legacyJSON(c) {
+		return rest.ReferenceError(err, nil)
+	}
+	return rest.ReferenceError(err, func(name string) string {
+		for legacy, current := range legacyTransactionMembers {
+			if current == name {
+				return legacy
+			}
+		}
+		return name
+	})
+}
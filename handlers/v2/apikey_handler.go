@@ -0,0 +1,119 @@
+package v2
+
+import (
+	"errors"
+	"net/http"
+
+	"lesson/auth"
+	"lesson/handlers/rest"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler manages the API keys machine clients authenticate with.
+type APIKeyHandler struct {
+	store storage.APIKeyStore
+}
+
+func NewAPIKeyHandler(store storage.APIKeyStore) *APIKeyHandler {
+	return &APIKeyHandler{store: store}
+}
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description Lists the API keys without their secrets. Revoked keys are only listed with include_revoked
+// @Tags api-keys
+// @Produce json
+// @Param include_revoked query bool false "Include revoked keys"
+// @Success 200 {array} v2.APIKeyResponse "API keys"
+// @Failure 400 {object} storage.ResponseError "Invalid include_revoked parameter"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission api_keys:manage"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/api-keys [get]
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	includeRevoked, err := rest.QueryBool(c, "include_revoked")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	keys, err := h.store.GetAPIKeys(c.Request.Context(), includeRevoked)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, rest.MapSlice(keys, apiKeyResponse))
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Generates an API key for a machine client. The key is only returned by this response; store it, as it cannot be retrieved again
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param input body v2.APIKeyRequest true "API key information"
+// @Success 201 {object} v2.CreatedAPIKeyResponse "Created API key, including the key"
+// @Failure 400 {object} storage.ResponseError "Malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission api_keys:manage"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var request APIKeyRequest
+	if err := rest.BindJSON(c, &request); err != nil {
+		c.Error(err)
+		return
+	}
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	createdKey, err := h.store.CreateAPIKey(c.Request.Context(), storage.APIKey{Name: request.Name, Role: request.Role, Prefix: prefix, Hash: hash})
+	if errors.Is(err, storage.ErrNotFound) {
+		c.Error(storage.ValidationError([]storage.FieldError{{Field: "role", Rule: "exists", Message: "role must name an existing role"}}))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, CreatedAPIKeyResponse{APIKeyResponse: apiKeyResponse(createdKey), Key: key})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Requests using the key are rejected from then on
+// @Tags api-keys
+// @Param id path int true "API key ID"
+// @Success 204 "API key revoked"
+// @Failure 400 {object} storage.ResponseError "Invalid API key ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission api_keys:manage"
+// @Failure 404 {object} storage.ResponseError "API key not found"
+// @Failure 409 {object} storage.ResponseError "API key is already revoked"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	keyID, err := pathID(c, "API key")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if _, err := h.store.RevokeAPIKey(c.Request.Context(), keyID); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,91 @@
+package v2_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"lesson/storage/storagetest"
+)
+
+func TestAuthorization(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "without a key", method: http.MethodGet, path: "/v2/customers", wantStatus: http.StatusUnauthorized, wantCode: "unauthenticated"},
+		{name: "unknown key", role: "-", method: http.MethodGet, path: "/v2/customers", wantStatus: http.StatusUnauthorized, wantCode: "unauthenticated"},
+		{name: "cashier reads customers", role: "cashier", method: http.MethodGet, path: "/v2/customers", wantStatus: http.StatusOK},
+		{name: "cashier sells", role: "cashier", method: http.MethodPost, path: "/v2/transactions", body: `{"customer_id":1,"item_id":1,"qty":1}`, wantStatus: http.StatusCreated},
+		{name: "cashier voids", role: "cashier", method: http.MethodPost, path: "/v2/transactions/1/void", wantStatus: http.StatusForbidden, wantCode: "forbidden"},
+		{name: "cashier tops up", role: "cashier", method: http.MethodPost, path: "/v2/customers/1/topups", body: `{"amount":"5.00"}`, wantStatus: http.StatusForbidden, wantCode: "forbidden"},
+		{name: "cashier lists API keys", role: "cashier", method: http.MethodGet, path: "/v2/api-keys", wantStatus: http.StatusForbidden, wantCode: "forbidden"},
+		{name: "manager voids", role: "manager", method: http.MethodPost, path: "/v2/transactions/1/void", wantStatus: http.StatusCreated},
+		{name: "admin lists API keys", role: "admin", method: http.MethodGet, path: "/v2/api-keys", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+				s := newServer(t, store)
+				s.seed("100.00", 5)
+				s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions", `{"customer_id":1,"item_id":1,"qty":1}`)
+
+				key := ""
+				switch tt.role {
+				case "":
+				case "-":
+					key = "lk_000000000000_unknown"
+				default:
+					key = s.apiKey(tt.role)
+				}
+				rec := s.do(tt.method, tt.path, tt.body, "X-API-Key", key)
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+				}
+				if tt.wantCode != "" && errorCode(t, rec) != tt.wantCode {
+					t.Errorf("code %q, want %q", errorCode(t, rec), tt.wantCode)
+				}
+			})
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+		s := newServer(t, store)
+
+		var created struct {
+			ID  int    `json:"id"`
+			Key string `json:"key"`
+		}
+		s.mustDo(http.StatusCreated, &created, http.MethodPost, "/v2/api-keys", `{"name":"checkout terminal 3","role":"cashier"}`)
+		s.mustDo(http.StatusOK, nil, http.MethodGet, "/v2/customers", "", "X-API-Key", created.Key)
+
+		var permissions struct {
+			Method string   `json:"method"`
+			Roles  []string `json:"roles"`
+		}
+		s.mustDo(http.StatusOK, &permissions, http.MethodGet, "/v2/me/permissions", "", "X-API-Key", created.Key)
+		if permissions.Method != "api_key" || len(permissions.Roles) != 1 || permissions.Roles[0] != "cashier" {
+			t.Errorf("permissions %+v", permissions)
+		}
+
+		s.mustDo(http.StatusNoContent, nil, http.MethodDelete, "/v2/api-keys/"+strconv.Itoa(created.ID), "")
+		if rec := s.do(http.MethodGet, "/v2/customers", "", "X-API-Key", created.Key); rec.Code != http.StatusUnauthorized {
+			t.Errorf("revoked key: status %d: %s", rec.Code, rec.Body)
+		}
+		if rec := s.do(http.MethodDelete, "/v2/api-keys/"+strconv.Itoa(created.ID), ""); rec.Code != http.StatusConflict {
+			t.Errorf("second revoke: status %d: %s", rec.Code, rec.Body)
+		}
+
+		rec := s.do(http.MethodPost, "/v2/api-keys", `{"name":"kiosk","role":"owner"}`)
+		if rec.Code != http.StatusUnprocessableEntity || errorCode(t, rec) != "validation_failed" {
+			t.Errorf("unknown role: status %d: %s", rec.Code, rec.Body)
+		}
+	})
+}
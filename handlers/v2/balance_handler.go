@@ -0,0 +1,149 @@
+package v2
+
+import (
+	"net/http"
+	"time"
+
+	"lesson/handlers/rest"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BalanceHandler serves the balance ledger of customers.
+type BalanceHandler struct {
+	store storage.BalanceStore
+}
+
+func NewBalanceHandler(store storage.BalanceStore) *BalanceHandler {
+	return &BalanceHandler{store: store}
+}
+
+// TopUp godoc
+// @Summary Top up a customer's balance
+// @Description Credits the balance and posts a top-up to the balance ledger
+// @Tags balance
+// @Accept json
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param input body v2.TopUpRequest true "Top-up"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v2.BalanceEntryResponse "Posted balance entry"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID or malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:write"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 409 {object} storage.ResponseError "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers/{id}/topups [post]
+func (h *BalanceHandler) TopUp(c *gin.Context) {
+	var request TopUpRequest
+	h.post(c, &request, func() storage.BalanceEntry { return request.entry() })
+}
+
+// AdjustBalance godoc
+// @Summary Adjust a customer's balance
+// @Description Corrects the balance by a signed amount and posts an adjustment with its reason to the balance ledger. The balance cannot go below zero
+// @Tags balance
+// @Accept json
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param input body v2.BalanceAdjustmentRequest true "Adjustment"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v2.BalanceEntryResponse "Posted balance entry"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID or malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:write"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 409 {object} storage.ResponseError "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed or insufficient balance"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers/{id}/balance-adjustments [post]
+func (h *BalanceHandler) AdjustBalance(c *gin.Context) {
+	var request BalanceAdjustmentRequest
+	h.post(c, &request, func() storage.BalanceEntry { return request.entry() })
+}
+
+// post binds the body into request and posts the entry that entry builds
+// from it for the customer in the id path parameter.
+func (h *BalanceHandler) post(c *gin.Context, request interface{}, entry func() storage.BalanceEntry) {
+	customerID, err := pathID(c, "customer")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := rest.BindJSON(c, request); err != nil {
+		c.Error(err)
+		return
+	}
+	balanceEntry := entry()
+	balanceEntry.CustomerID = customerID
+	postedEntry, err := h.store.AddBalanceEntry(c.Request.Context(), balanceEntry)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, balanceEntryResponse(postedEntry))
+}
+
+// GetStatement godoc
+// @Summary Get a customer's balance statement
+// @Description Returns the opening balance at from, the balance entries posted from from up to to, and the closing balance at to. Deleted customers keep their statements
+// @Tags balance
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param from query string false "Start of the period, an RFC 3339 timestamp or a YYYY-MM-DD date (default: the first entry)"
+// @Param to query string false "End of the period, exclusive; a YYYY-MM-DD date includes that day (default: now)"
+// @Success 200 {object} v2.BalanceStatementResponse "Balance statement"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID or period"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:read"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers/{id}/statement [get]
+func (h *BalanceHandler) GetStatement(c *gin.Context) {
+	customerID, err := pathID(c, "customer")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	from, err := rest.QueryTime(c, "from", false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	to, err := rest.QueryTime(c, "to", true)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if from == nil {
+		from = &time.Time{}
+	}
+	if to == nil {
+		now := time.Now()
+		to = &now
+	}
+	if !from.Before(*to) {
+		c.Error(rest.InvalidParam("to", "must be after from"))
+		return
+	}
+	statement, err := h.store.GetBalanceStatement(c.Request.Context(), customerID, *from, *to)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, balanceStatementResponse(statement))
+}
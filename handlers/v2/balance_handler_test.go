@@ -0,0 +1,72 @@
+package v2_test
+
+import (
+	"net/http"
+	"testing"
+
+	"lesson/storage/storagetest"
+)
+
+func TestBalanceStatement(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+		s := newServer(t, store)
+		s.seed("100.00", 5)
+		s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/customers/1/topups", `{"amount":"50.00","reason":"cash at till 3"}`)
+		s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions", `{"customer_id":1,"item_id":1,"qty":2}`)
+		s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions/1/refunds", `{"qty":1,"reason":"wrong size"}`)
+		s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/customers/1/balance-adjustments", `{"amount":"-5.00","reason":"goodwill credit reversed"}`)
+
+		var statement struct {
+			OpeningBalance string `json:"opening_balance"`
+			ClosingBalance string `json:"closing_balance"`
+			Entries        []struct {
+				Kind         string `json:"kind"`
+				Amount       string `json:"amount"`
+				BalanceAfter string `json:"balance_after"`
+			} `json:"entries"`
+		}
+		s.mustDo(http.StatusOK, &statement, http.MethodGet, "/v2/customers/1/statement", "")
+		want := []struct{ kind, amount, after string }{
+			{"topup", "100.00", "100.00"},
+			{"topup", "50.00", "150.00"},
+			{"purchase", "-20.00", "130.00"},
+			{"refund", "10.00", "140.00"},
+			{"adjustment", "-5.00", "135.00"},
+		}
+		if len(statement.Entries) != len(want) {
+			t.Fatalf("%d entries, want %d", len(statement.Entries), len(want))
+		}
+		for i, w := range want {
+			e := statement.Entries[i]
+			if e.Kind != w.kind || e.Amount != w.amount || e.BalanceAfter != w.after {
+				t.Errorf("entry %d: %+v, want %+v", i, e, w)
+			}
+		}
+		if statement.OpeningBalance != "0.00" || statement.ClosingBalance != "135.00" {
+			t.Errorf("opening %s, closing %s", statement.OpeningBalance, statement.ClosingBalance)
+		}
+		if balance, _ := s.holdings(); balance != "135.00" {
+			t.Errorf("balance %s, want the closing balance", balance)
+		}
+
+		tests := []struct {
+			name       string
+			method     string
+			path       string
+			body       string
+			wantStatus int
+			wantCode   string
+		}{
+			{name: "negative top-up", method: http.MethodPost, path: "/v2/customers/1/topups", body: `{"amount":"-1.00"}`, wantStatus: http.StatusUnprocessableEntity, wantCode: "validation_failed"},
+			{name: "adjustment below zero", method: http.MethodPost, path: "/v2/customers/1/balance-adjustments", body: `{"amount":"-500.00","reason":"typo"}`, wantStatus: http.StatusUnprocessableEntity, wantCode: "insufficient_balance"},
+			{name: "unknown customer", method: http.MethodPost, path: "/v2/customers/9/topups", body: `{"amount":"1.00"}`, wantStatus: http.StatusNotFound, wantCode: "customer_not_found"},
+			{name: "empty period", method: http.MethodGet, path: "/v2/customers/1/statement?from=2024-02-01&to=2024-01-01", wantStatus: http.StatusBadRequest, wantCode: "invalid_parameter"},
+		}
+		for _, tt := range tests {
+			rec := s.do(tt.method, tt.path, tt.body)
+			if rec.Code != tt.wantStatus || errorCode(t, rec) != tt.wantCode {
+				t.Errorf("%s: status %d: %s", tt.name, rec.Code, rec.Body)
+			}
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package v2
+
+import (
+	"net/http"
+
+	v1 "lesson/handlers/v1"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CustomerHandler struct {
+	v1    *v1.CustomerHandler
+	store storage.CustomerStore
+}
+
+func NewCustomerHandler(store storage.CustomerStore) *CustomerHandler {
+	return &CustomerHandler{v1: v1.NewCustomerHandler(store).WithLocation("/v2/customers"), store: store}
+}
+
+// GetCustomers godoc
+// @Summary List customers
+// @Tags customers
+// @Produce json
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param include_deleted query bool false "Include soft-deleted customers"
+// @Success 200 {object} storage.Page[v1.CustomerResponse] "Page of customers"
+// @Failure 400 {object} storage.ResponseError "Invalid pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:read"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers [get]
+func (h *CustomerHandler) GetCustomers(c *gin.Context) {
+	h.v1.GetCustomers(c)
+}
+
+// CreateCustomer godoc
+// @Summary Create a customer
+// @Description A non-zero balance is posted as the customer's first top-up
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param input body v1.CustomerRequest true "Customer information"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v1.CustomerResponse "Created customer"
+// @Header 201 {string} Location "Path of the created customer"
+// @Header 201 {string} ETag "Version of the created customer"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
+// @Failure 400 {object} storage.ResponseError "Malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:write"
+// @Failure 409 {object} storage.ResponseError "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers [post]
+func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
+	h.v1.CreateCustomer(c)
+}
+
+// GetCustomer godoc
+// @Summary Get a customer
+// @Tags customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param include_deleted query bool false "Return the customer even if it is soft-deleted"
+// @Success 200 {object} v1.CustomerResponse "Customer details"
+// @Header 200 {string} ETag "Version of the customer, for If-Match"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:read"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers/{id} [get]
+func (h *CustomerHandler) GetCustomer(c *gin.Context) {
+	h.v1.GetCustomer(c)
+}
+
+// UpdateCustomer godoc
+// @Summary Replace a customer
+// @Description The balance is read-only; it changes through top-ups and adjustments
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param If-Match header string false "ETag of the customer as last read; the update fails with 412 if it changed since"
+// @Param input body v1.CustomerUpdateRequest true "Customer information"
+// @Success 200 {object} v1.CustomerResponse "Updated customer"
+// @Header 200 {string} ETag "Version of the updated customer"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID or malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:write"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 409 {object} storage.ResponseError "Customer was modified since the version in the body"
+// @Failure 412 {object} storage.ResponseError "Customer was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers/{id} [put]
+func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
+	h.v1.UpdateCustomer(c)
+}
+
+// PatchCustomer godoc
+// @Summary Partially update a customer
+// @Description Applies an RFC 7396 JSON merge patch; members that are absent keep their value. The balance is read-only
+// @Tags customers
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param If-Match header string false "ETag of the customer as last read; the update fails with 412 if it changed since"
+// @Param patch body v1.CustomerUpdateRequest true "Members to change"
+// @Success 200 {object} v1.CustomerResponse "Updated customer"
+// @Header 200 {string} ETag "Version of the updated customer"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID or malformed patch"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:write"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 409 {object} storage.ResponseError "Customer was modified since the version in the patch"
+// @Failure 412 {object} storage.ResponseError "Customer was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers/{id} [patch]
+func (h *CustomerHandler) PatchCustomer(c *gin.Context) {
+	h.v1.PatchCustomer(c)
+}
+
+// DeleteCustomer godoc
+// @Summary Delete a customer
+// @Description Soft deletes a customer; it can be restored
+// @Tags customers
+// @Param id path int true "Customer ID"
+// @Success 204 "Customer deleted"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:delete"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers/{id} [delete]
+func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
+	customerID, err := pathID(c, "customer")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if _, err := h.store.DeleteCustomer(c.Request.Context(), customerID); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreCustomer godoc
+// @Summary Restore a deleted customer
+// @Tags customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Success 200 {object} v1.CustomerResponse "Restored customer"
+// @Header 200 {string} ETag "Version of the restored customer"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission customers:delete"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 409 {object} storage.ResponseError "Customer is not deleted"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers/{id}/restore [post]
+func (h *CustomerHandler) RestoreCustomer(c *gin.Context) {
+	h.v1.RestoreCustomer(c)
+}
@@ -0,0 +1,187 @@
+package v2
+
+import (
+	"net/http"
+
+	v1 "lesson/handlers/v1"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ItemHandler struct {
+	v1    *v1.ItemHandler
+	store storage.ItemStore
+}
+
+func NewItemHandler(store storage.ItemStore) *ItemHandler {
+	return &ItemHandler{v1: v1.NewItemHandler(store).WithLocation("/v2/items"), store: store}
+}
+
+// GetItems godoc
+// @Summary List items
+// @Tags items
+// @Produce json
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param include_deleted query bool false "Include soft-deleted items"
+// @Success 200 {object} storage.Page[v1.ItemResponse] "Page of items"
+// @Failure 400 {object} storage.ResponseError "Invalid pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:read"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/items [get]
+func (h *ItemHandler) GetItems(c *gin.Context) {
+	h.v1.GetItems(c)
+}
+
+// CreateItem godoc
+// @Summary Create an item
+// @Tags items
+// @Accept json
+// @Produce json
+// @Param input body v1.ItemRequest true "Item information"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v1.ItemResponse "Created item"
+// @Header 201 {string} Location "Path of the created item"
+// @Header 201 {string} ETag "Version of the created item"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
+// @Failure 400 {object} storage.ResponseError "Malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:write"
+// @Failure 409 {object} storage.ResponseError "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/items [post]
+func (h *ItemHandler) CreateItem(c *gin.Context) {
+	h.v1.CreateItem(c)
+}
+
+// GetItem godoc
+// @Summary Get an item
+// @Tags items
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param include_deleted query bool false "Return the item even if it is soft-deleted"
+// @Success 200 {object} v1.ItemResponse "Item details"
+// @Header 200 {string} ETag "Version of the item, for If-Match"
+// @Failure 400 {object} storage.ResponseError "Invalid item ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:read"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/items/{id} [get]
+func (h *ItemHandler) GetItem(c *gin.Context) {
+	h.v1.GetItem(c)
+}
+
+// UpdateItem godoc
+// @Summary Replace an item
+// @Tags items
+// @Accept json
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param If-Match header string false "ETag of the item as last read; the update fails with 412 if it changed since"
+// @Param input body v1.ItemUpdateRequest true "Item information"
+// @Success 200 {object} v1.ItemResponse "Updated item"
+// @Header 200 {string} ETag "Version of the updated item"
+// @Failure 400 {object} storage.ResponseError "Invalid item ID or malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:write"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 409 {object} storage.ResponseError "Item was modified since the version in the body"
+// @Failure 412 {object} storage.ResponseError "Item was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/items/{id} [put]
+func (h *ItemHandler) UpdateItem(c *gin.Context) {
+	h.v1.UpdateItem(c)
+}
+
+// PatchItem godoc
+// @Summary Partially update an item
+// @Description Applies an RFC 7396 JSON merge patch; members that are absent keep their value
+// @Tags items
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param If-Match header string false "ETag of the item as last read; the update fails with 412 if it changed since"
+// @Param patch body v1.ItemUpdateRequest true "Members to change"
+// @Success 200 {object} v1.ItemResponse "Updated item"
+// @Header 200 {string} ETag "Version of the updated item"
+// @Failure 400 {object} storage.ResponseError "Invalid item ID or malformed patch"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:write"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 409 {object} storage.ResponseError "Item was modified since the version in the patch"
+// @Failure 412 {object} storage.ResponseError "Item was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed; see fields"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/items/{id} [patch]
+func (h *ItemHandler) PatchItem(c *gin.Context) {
+	h.v1.PatchItem(c)
+}
+
+// DeleteItem godoc
+// @Summary Delete an item
+// @Description Soft deletes an item; it can be restored
+// @Tags items
+// @Param id path int true "Item ID"
+// @Success 204 "Item deleted"
+// @Failure 400 {object} storage.ResponseError "Invalid item ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:delete"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/items/{id} [delete]
+func (h *ItemHandler) DeleteItem(c *gin.Context) {
+	itemID, err := pathID(c, "item")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if _, err := h.store.DeleteItem(c.Request.Context(), itemID); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreItem godoc
+// @Summary Restore a deleted item
+// @Tags items
+// @Produce json
+// @Param id path int true "Item ID"
+// @Success 200 {object} v1.ItemResponse "Restored item"
+// @Header 200 {string} ETag "Version of the restored item"
+// @Failure 400 {object} storage.ResponseError "Invalid item ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:delete"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 409 {object} storage.ResponseError "Item is not deleted"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/items/{id}/restore [post]
+func (h *ItemHandler) RestoreItem(c *gin.Context) {
+	h.v1.RestoreItem(c)
+}
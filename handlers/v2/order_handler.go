@@ -0,0 +1,125 @@
+package v2
+
+import (
+	"net/http"
+	"strconv"
+
+	"lesson/handlers/rest"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrderHandler serves orders with their lines.
+type OrderHandler struct {
+	store storage.OrderStore
+}
+
+func NewOrderHandler(store storage.OrderStore) *OrderHandler {
+	return &OrderHandler{store: store}
+}
+
+// CreateOrder godoc
+// @Summary Place an order
+// @Description Records the purchase of several items at once. Every line becomes a sale at the item's current price, debited from the customer's balance and booked in the stock ledger; the order is placed in full or not at all. Errors of a line name it, e.g. lines.1.item_id
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param input body v2.OrderRequest true "Customer and lines of the order"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v2.OrderResponse "Placed order"
+// @Header 201 {string} Location "Path of the placed order"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
+// @Failure 400 {object} storage.ResponseError "Malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:create"
+// @Failure 409 {object} storage.ResponseError "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed, unknown customer or item, insufficient balance or insufficient stock"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/orders [post]
+func (h *OrderHandler) CreateOrder(c *gin.Context) {
+	var request OrderRequest
+	if err := rest.BindJSON(c, &request); err != nil {
+		c.Error(err)
+		return
+	}
+	createdOrder, err := h.store.CreateOrder(c.Request.Context(), request.order())
+	if err != nil {
+		c.Error(rest.ReferenceError(err, nil))
+		return
+	}
+	// Orders have no version, so unlike other creates the answer carries no
+	// ETag.
+	c.Header("Location", "/v2/orders/"+strconv.Itoa(createdOrder.ID))
+	c.JSON(http.StatusCreated, orderResponse(createdOrder))
+}
+
+// GetOrder godoc
+// @Summary Get an order
+// @Description Returns an order with its lines and total. Deleted lines are left out; voids and refunds are separate transactions that name their line, and the total is net of them
+// @Tags orders
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} v2.OrderResponse "Order with its lines"
+// @Failure 400 {object} storage.ResponseError "Invalid order ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
+// @Failure 404 {object} storage.ResponseError "Order not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/orders/{id} [get]
+func (h *OrderHandler) GetOrder(c *gin.Context) {
+	orderID, err := pathID(c, "order")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	order, err := h.store.GetOrder(c.Request.Context(), orderID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, orderResponse(order))
+}
+
+// GetCustomerOrders godoc
+// @Summary List the orders of a customer
+// @Description Lists the orders of a customer with their lines, oldest first. Sales made before orders existed are one-line orders. Totals are net of the voids and refunds of the lines
+// @Tags orders
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} storage.Page[v2.OrderResponse] "Page of orders"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID or pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers/{id}/orders [get]
+func (h *OrderHandler) GetCustomerOrders(c *gin.Context) {
+	customerID, err := pathID(c, "customer")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	page, err := rest.PageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	orders, err := h.store.GetCustomerOrders(c.Request.Context(), customerID, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, rest.MapPage(orders, orderResponse))
+}
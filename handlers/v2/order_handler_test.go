@@ -0,0 +1,100 @@
+package v2_test
+
+import (
+	"net/http"
+	"testing"
+
+	"lesson/storage/storagetest"
+)
+
+func TestCreateOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantCode    string
+		wantField   string
+		wantTotal   string
+		wantBalance string
+		wantStock   int
+	}{
+		{name: "books every line", body: `{"customer_id":1,"lines":[{"item_id":1,"qty":2},{"item_id":1,"qty":1}]}`, wantStatus: http.StatusCreated, wantTotal: "30.00", wantBalance: "70.00", wantStock: 2},
+		{name: "unknown item on a line", body: `{"customer_id":1,"lines":[{"item_id":1,"qty":2},{"item_id":9,"qty":1}]}`, wantStatus: http.StatusUnprocessableEntity, wantCode: "validation_failed", wantField: "lines.1.item_id", wantBalance: "100.00", wantStock: 5},
+		{name: "invalid line", body: `{"customer_id":1,"lines":[{"item_id":1,"qty":0}]}`, wantStatus: http.StatusUnprocessableEntity, wantCode: "validation_failed", wantField: "lines.0.qty", wantBalance: "100.00", wantStock: 5},
+		{name: "line out of stock", body: `{"customer_id":1,"lines":[{"item_id":1,"qty":4},{"item_id":1,"qty":2}]}`, wantStatus: http.StatusUnprocessableEntity, wantCode: "insufficient_stock", wantBalance: "100.00", wantStock: 5},
+		{name: "unknown customer", body: `{"customer_id":9,"lines":[{"item_id":1,"qty":1}]}`, wantStatus: http.StatusUnprocessableEntity, wantCode: "validation_failed", wantField: "customer_id", wantBalance: "100.00", wantStock: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+				s := newServer(t, store)
+				s.seed("100.00", 5)
+
+				rec := s.do(http.MethodPost, "/v2/orders", tt.body)
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+				}
+				var body struct {
+					Total  string `json:"total"`
+					Code   string `json:"code"`
+					Fields []struct {
+						Field string `json:"field"`
+					} `json:"fields"`
+				}
+				decode(t, rec, &body)
+				if body.Total != tt.wantTotal || body.Code != tt.wantCode {
+					t.Errorf("total %q and code %q, want %q and %q", body.Total, body.Code, tt.wantTotal, tt.wantCode)
+				}
+				if tt.wantField != "" && (len(body.Fields) != 1 || body.Fields[0].Field != tt.wantField) {
+					t.Errorf("fields %+v, want %s", body.Fields, tt.wantField)
+				}
+				if balance, stock := s.holdings(); balance != tt.wantBalance || stock != tt.wantStock {
+					t.Errorf("balance %s and stock %d, want %s and %d", balance, stock, tt.wantBalance, tt.wantStock)
+				}
+			})
+		})
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+		s := newServer(t, store)
+		s.seed("100.00", 5)
+		rec := s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/orders", `{"customer_id":1,"lines":[{"item_id":1,"qty":2},{"item_id":1,"qty":1}]}`)
+		if got := rec.Header().Get("Location"); got != "/v2/orders/1" {
+			t.Errorf("Location %q", got)
+		}
+		s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions/2/refunds", `{"qty":1,"reason":"wrong size"}`)
+
+		var order struct {
+			Total string `json:"total"`
+			Lines []struct {
+				TransactionID int `json:"transaction_id"`
+			} `json:"lines"`
+		}
+		s.mustDo(http.StatusOK, &order, http.MethodGet, "/v2/orders/1", "")
+		if order.Total != "20.00" || len(order.Lines) != 2 {
+			t.Errorf("order %+v, want a total of 20.00 after the refund", order)
+		}
+
+		var page struct {
+			Data []struct {
+				ID int `json:"id"`
+			} `json:"data"`
+			NextCursor string `json:"next_cursor"`
+		}
+		s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/orders", `{"customer_id":1,"lines":[{"item_id":1,"qty":1}]}`)
+		s.mustDo(http.StatusOK, &page, http.MethodGet, "/v2/customers/1/orders?limit=1", "")
+		if len(page.Data) != 1 || page.Data[0].ID != 1 || page.NextCursor == "" {
+			t.Fatalf("first page %+v", page)
+		}
+		s.mustDo(http.StatusOK, &page, http.MethodGet, "/v2/customers/1/orders?limit=1&cursor="+page.NextCursor, "")
+		if len(page.Data) != 1 || page.Data[0].ID != 2 || page.NextCursor != "" {
+			t.Errorf("second page %+v", page)
+		}
+
+		if rec := s.do(http.MethodGet, "/v2/orders/9", ""); rec.Code != http.StatusNotFound {
+			t.Errorf("unknown order: status %d: %s", rec.Code, rec.Body)
+		}
+	})
+}
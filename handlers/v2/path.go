@@ -0,0 +1,22 @@
+// Package v2 serves the resource-oriented API under /v2. Reads, updates and
+// restores behave as in v1; the handlers differ where v2 follows HTTP
+// conventions more closely: creates name the new resource in a Location
+// header and deletes answer 204 No Content. Orders, the balance and stock
+// ledgers, voids and refunds, and API keys exist only in v2.
+package v2
+
+import (
+	"strconv"
+
+	"lesson/handlers/rest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func pathID(c *gin.Context, entity string) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, rest.InvalidID(entity)
+	}
+	return id, nil
+}
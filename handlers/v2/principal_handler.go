@@ -0,0 +1,59 @@
+package v2
+
+import (
+	"net/http"
+
+	"lesson/auth"
+	"lesson/handlers/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrincipalHandler answers questions of callers about themselves.
+type PrincipalHandler struct{}
+
+func NewPrincipalHandler() *PrincipalHandler {
+	return &PrincipalHandler{}
+}
+
+// GetPermissions godoc
+// @Summary List the permissions of the caller
+// @Description Returns the roles of the API key or bearer token the request was made with and the permissions they grant. Needs no permission itself. When the server runs without authentication, method is none and both lists are empty
+// @Tags auth
+// @Produce json
+// @Success 200 {object} v2.PermissionsResponse "Roles and effective permissions"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/me/permissions [get]
+func (h *PrincipalHandler) GetPermissions(c *gin.Context) {
+	principal, ok := middleware.Principal(c)
+	if !ok {
+		principal = auth.Principal{Method: auth.MethodNone}
+	}
+	c.JSON(http.StatusOK, permissionsResponse(principal))
+}
+
+// PermissionsResponse describes the caller of a request and what it may do.
+type PermissionsResponse struct {
+	Subject     string   `json:"subject" example:"api_key:1"`
+	Method      string   `json:"method" enums:"api_key,jwt,none" example:"api_key"`
+	Roles       []string `json:"roles" example:"cashier"`
+	Permissions []string `json:"permissions" example:"customers:read,items:read,transactions:create,transactions:read"`
+}
+
+func permissionsResponse(principal auth.Principal) PermissionsResponse {
+	response := PermissionsResponse{
+		Subject:     principal.Subject,
+		Method:      string(principal.Method),
+		Roles:       make([]string, len(principal.Roles)),
+		Permissions: make([]string, len(principal.Permissions)),
+	}
+	copy(response.Roles, principal.Roles)
+	for i, permission := range principal.Permissions {
+		response.Permissions[i] = string(permission)
+	}
+	return response
+}
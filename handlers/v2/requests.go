@@ -0,0 +1,79 @@
+package v2
+
+import (
+	"lesson/storage"
+)
+
+// TopUpRequest is the body of a top-up of a customer's balance.
+type TopUpRequest struct {
+	Amount storage.Money `json:"amount" binding:"gt=0,lt=1000000000000" swaggertype:"string" example:"50.00"`
+	Reason string        `json:"reason" binding:"max=255" example:"cash at till 3"`
+}
+
+func (r TopUpRequest) entry() storage.BalanceEntry {
+	return storage.BalanceEntry{Kind: storage.BalanceTopUp, Amount: r.Amount, Reason: r.Reason}
+}
+
+// BalanceAdjustmentRequest is the body of a manual correction of a
+// customer's balance. Amount is signed; the reason is kept in the ledger.
+type BalanceAdjustmentRequest struct {
+	Amount storage.Money `json:"amount" binding:"required,gt=-1000000000000,lt=1000000000000" swaggertype:"string" example:"-5.00"`
+	Reason string        `json:"reason" binding:"required,max=255" example:"goodwill credit reversed"`
+}
+
+func (r BalanceAdjustmentRequest) entry() storage.BalanceEntry {
+	return storage.BalanceEntry{Kind: storage.BalanceAdjustment, Amount: r.Amount, Reason: r.Reason}
+}
+
+// APIKeyRequest is the body of an API key create request. Name says who or
+// what uses the key; Role decides what it may do.
+type APIKeyRequest struct {
+	Name string `json:"name" binding:"required,max=255" example:"checkout terminal 3"`
+	Role string `json:"role" binding:"required" example:"cashier"`
+}
+
+// OrderRequest is the body of an order. Prices and line totals are taken
+// from the items when the order is placed.
+type OrderRequest struct {
+	CustomerID int                `json:"customer_id" binding:"required,gte=1" example:"1"`
+	Lines      []OrderLineRequest `json:"lines" binding:"required,min=1,max=100"`
+}
+
+// OrderLineRequest is a line of an order.
+type OrderLineRequest struct {
+	ItemID int `json:"item_id" binding:"required,gte=1" example:"1"`
+	Qty    int `json:"qty" binding:"required,gte=1,lt=1000000" example:"2"`
+}
+
+func (r OrderRequest) order() storage.Order {
+	order := storage.Order{CustomerID: r.CustomerID}
+	for _, line := range r.Lines {
+		order.Lines = append(order.Lines, storage.OrderLine{ItemID: line.ItemID, Qty: line.Qty})
+	}
+	return order
+}
+
+// VoidRequest is the optional body of a void.
+type VoidRequest struct {
+	Reason string `json:"reason" binding:"max=255" example:"rung up twice"`
+}
+
+// RefundRequest is the body of a refund of some of the units of a sale.
+type RefundRequest struct {
+	Qty    int    `json:"qty" binding:"required,gte=1,lt=1000000" example:"1"`
+	Reason string `json:"reason" binding:"required,max=255" example:"wrong size"`
+}
+
+// StockMovementRequest is the body of a stock movement booked by hand. Qty
+// is the signed change of the stock: restocks and returns add stock,
+// adjustments, e.g. after a stocktake, may go either way and need a reason.
+// Sales are booked by transactions.
+type StockMovementRequest struct {
+	Kind   string `json:"kind" binding:"required,oneof=restock adjustment return" enums:"restock,adjustment,return" example:"restock"`
+	Qty    int    `json:"qty" binding:"required,gt=-1000000,lt=1000000" example:"10"`
+	Reason string `json:"reason" binding:"max=255" example:"delivery 2024-118"`
+}
+
+func (r StockMovementRequest) movement() storage.StockMovement {
+	return storage.StockMovement{Kind: r.Kind, Qty: r.Qty, Reason: r.Reason}
+}
@@ -0,0 +1,175 @@
+package v2
+
+import (
+	"time"
+
+	"lesson/handlers/rest"
+	"lesson/storage"
+)
+
+// APIKeyResponse is an API key as the API lists it. The key itself is only
+// returned once, by CreatedAPIKeyResponse.
+type APIKeyResponse struct {
+	ID         int        `json:"id" example:"1"`
+	Name       string     `json:"name" example:"checkout terminal 3"`
+	Role       string     `json:"role" example:"cashier"`
+	Prefix     string     `json:"prefix" example:"lk_3f9a0c5e21b4"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at" extensions:"x-nullable"`
+	RevokedAt  *time.Time `json:"revoked_at" extensions:"x-nullable"`
+}
+
+// CreatedAPIKeyResponse is a new API key including its plaintext key, which
+// cannot be retrieved again.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key" example:"lk_3f9a0c5e21b4_Wm9vbGFuZGVyLXNlY3JldC1vbmx5LXNob3duLW9uY2U"`
+}
+
+func apiKeyResponse(key storage.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Role:       key.Role,
+		Prefix:     key.Prefix,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+// OrderResponse is an order with its lines as the API returns it. Total is
+// the sum of the line totals less what voids and refunds of the lines
+// returned.
+type OrderResponse struct {
+	ID         int                 `json:"id" example:"1"`
+	CustomerID int                 `json:"customer_id" example:"1"`
+	Lines      []OrderLineResponse `json:"lines"`
+	Total      storage.Money       `json:"total" swaggertype:"string" example:"2425.00"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// OrderLineResponse is a line of an order: the sale that records it, the
+// unit price at the time of sale and the line total.
+type OrderLineResponse struct {
+	TransactionID int           `json:"transaction_id" example:"1"`
+	ItemID        int           `json:"item_id" example:"1"`
+	Qty           int           `json:"qty" example:"2"`
+	Price         storage.Money `json:"price" swaggertype:"string" example:"1200.00"`
+	Amount        storage.Money `json:"amount" swaggertype:"string" example:"2400.00"`
+}
+
+func orderResponse(order storage.Order) OrderResponse {
+	return OrderResponse{
+		ID:         order.ID,
+		CustomerID: order.CustomerID,
+		Lines:      rest.MapSlice(order.Lines, orderLineResponse),
+		Total:      order.Total,
+		CreatedAt:  order.CreatedAt,
+	}
+}
+
+func orderLineResponse(line storage.OrderLine) OrderLineResponse {
+	return OrderLineResponse{
+		TransactionID: line.TransactionID,
+		ItemID:        line.ItemID,
+		Qty:           line.Qty,
+		Price:         line.Price,
+		Amount:        line.Amount,
+	}
+}
+
+// BalanceEntryResponse is an entry of the balance ledger of a customer as
+// the API returns it.
+type BalanceEntryResponse struct {
+	ID            int           `json:"id" example:"1"`
+	CustomerID    int           `json:"customer_id" example:"1"`
+	Kind          string        `json:"kind" enums:"topup,purchase,refund,adjustment" example:"topup"`
+	Amount        storage.Money `json:"amount" swaggertype:"string" example:"50.00"`
+	BalanceAfter  storage.Money `json:"balance_after" swaggertype:"string" example:"1050.00"`
+	TransactionID *int          `json:"transaction_id" extensions:"x-nullable" example:"1"`
+	Reason        string        `json:"reason" example:"cash at till 3"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+func balanceEntryResponse(entry storage.BalanceEntry) BalanceEntryResponse {
+	return BalanceEntryResponse{
+		ID:            entry.ID,
+		CustomerID:    entry.CustomerID,
+		Kind:          entry.Kind,
+		Amount:        entry.Amount,
+		BalanceAfter:  entry.BalanceAfter,
+		TransactionID: entry.TransactionID,
+		Reason:        entry.Reason,
+		CreatedAt:     entry.CreatedAt,
+	}
+}
+
+// BalanceStatementResponse is the statement of a customer's balance for a
+// period. From is null for a statement since the first entry.
+type BalanceStatementResponse struct {
+	CustomerID     int                    `json:"customer_id" example:"1"`
+	From           *time.Time             `json:"from" extensions:"x-nullable"`
+	To             time.Time              `json:"to"`
+	OpeningBalance storage.Money          `json:"opening_balance" swaggertype:"string" example:"1000.00"`
+	Entries        []BalanceEntryResponse `json:"entries"`
+	ClosingBalance storage.Money          `json:"closing_balance" swaggertype:"string" example:"1050.00"`
+}
+
+func balanceStatementResponse(statement storage.BalanceStatement) BalanceStatementResponse {
+	response := BalanceStatementResponse{
+		CustomerID:     statement.CustomerID,
+		To:             statement.To,
+		OpeningBalance: statement.OpeningBalance,
+		Entries:        rest.MapSlice(statement.Entries, balanceEntryResponse),
+		ClosingBalance: statement.ClosingBalance,
+	}
+	if !statement.From.IsZero() {
+		response.From = &statement.From
+	}
+	return response
+}
+
+// StockResponse is the stock of an item. Backordered counts the units sold
+// that were not in stock when the item allows backorders.
+type StockResponse struct {
+	ItemID         int  `json:"item_id" example:"1"`
+	Stock          int  `json:"stock" example:"25"`
+	AllowBackorder bool `json:"allow_backorder" example:"false"`
+	Backordered    int  `json:"backordered" example:"0"`
+}
+
+func stockResponse(item storage.Item) StockResponse {
+	return StockResponse{
+		ItemID:         item.ID,
+		Stock:          item.Stock,
+		AllowBackorder: item.AllowBackorder,
+		Backordered:    max(-item.Stock, 0),
+	}
+}
+
+// StockMovementResponse is an entry of the stock ledger of an item as the API
+// returns it.
+type StockMovementResponse struct {
+	ID            int       `json:"id" example:"1"`
+	ItemID        int       `json:"item_id" example:"1"`
+	Kind          string    `json:"kind" enums:"sale,restock,adjustment,return" example:"sale"`
+	Qty           int       `json:"qty" example:"-2"`
+	StockAfter    int       `json:"stock_after" example:"23"`
+	TransactionID *int      `json:"transaction_id" extensions:"x-nullable" example:"1"`
+	Reason        string    `json:"reason" example:""`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func stockMovementResponse(movement storage.StockMovement) StockMovementResponse {
+	return StockMovementResponse{
+		ID:            movement.ID,
+		ItemID:        movement.ItemID,
+		Kind:          movement.Kind,
+		Qty:           movement.Qty,
+		StockAfter:    movement.StockAfter,
+		TransactionID: movement.TransactionID,
+		Reason:        movement.Reason,
+		CreatedAt:     movement.CreatedAt,
+	}
+}
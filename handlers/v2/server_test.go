@@ -0,0 +1,127 @@
+package v2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"lesson/auth"
+	"lesson/config"
+	api "lesson/handlers"
+	"lesson/storage"
+	"lesson/storage/storagetest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// server is the router of the whole API on a store, called with the API key
+// of an admin unless a request names another one.
+type server struct {
+	t      *testing.T
+	router *gin.Engine
+	store  storagetest.Store
+	key    string
+}
+
+func newServer(t *testing.T, store storagetest.Store) *server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.OpenAPI.ValidateResponses = true
+	s := &server{t: t, router: api.SetupRouter(cfg, store, store, store, store, store, store, store, store, store), store: store}
+	s.key = s.apiKey("admin")
+	return s
+}
+
+// apiKey creates a key with role and returns its plaintext.
+func (s *server) apiKey(role string) string {
+	s.t.Helper()
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if _, err := s.store.CreateAPIKey(context.Background(), storage.APIKey{Name: "test " + role, Role: role, Prefix: prefix, Hash: hash}); err != nil {
+		s.t.Fatal(err)
+	}
+	return key
+}
+
+// do sends a request with a JSON body, unless body is empty, and the header
+// given as name and value pairs.
+func (s *server) do(method, path, body string, header ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+		if method == http.MethodPatch {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		}
+	}
+	req.Header.Set("X-API-Key", s.key)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// mustDo sends a request like do and fails the test unless it answers with
+// status. The body is decoded into out unless out is nil.
+func (s *server) mustDo(status int, out interface{}, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	rec := s.do(method, path, body, header...)
+	if rec.Code != status {
+		s.t.Fatalf("%s %s: status %d, want %d: %s", method, path, rec.Code, status, rec.Body)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return rec
+}
+
+// seed creates a customer with balance and an item priced 10.00 with stock
+// units in stock.
+func (s *server) seed(balance string, stock int) {
+	s.t.Helper()
+	s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/customers", `{"customer_name":"John Doe","balance":"`+balance+`"}`)
+	s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/items", `{"item_name":"Laptop","price":"10.00"}`)
+	s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/items/1/stock-movements", `{"kind":"restock","qty":`+strconv.Itoa(stock)+`}`)
+}
+
+// holdings returns the balance of customer 1 and the stock of item 1.
+func (s *server) holdings() (string, int) {
+	s.t.Helper()
+	var customer struct {
+		Balance string `json:"balance"`
+	}
+	s.mustDo(http.StatusOK, &customer, http.MethodGet, "/v2/customers/1", "")
+	var stock struct {
+		Stock int `json:"stock"`
+	}
+	s.mustDo(http.StatusOK, &stock, http.MethodGet, "/v2/items/1/stock", "")
+	return customer.Balance, stock.Stock
+}
+
+// errorCode returns the code of an error response.
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body storage.ResponseError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body %s: %v", rec.Body, err)
+	}
+	return body.Code
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, out interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+		t.Fatalf("body %s: %v", rec.Body, err)
+	}
+}
@@ -0,0 +1,130 @@
+package v2
+
+import (
+	"net/http"
+
+	"lesson/handlers/rest"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StockHandler serves the stock of items and their stock ledger.
+type StockHandler struct {
+	items storage.ItemStore
+	stock storage.StockStore
+}
+
+func NewStockHandler(items storage.ItemStore, stock storage.StockStore) *StockHandler {
+	return &StockHandler{items: items, stock: stock}
+}
+
+// GetStock godoc
+// @Summary Get the stock of an item
+// @Description Returns the quantity on hand. It goes below zero only for items that allow backorders; backordered counts the units sold that were not in stock
+// @Tags stock
+// @Produce json
+// @Param id path int true "Item ID"
+// @Success 200 {object} v2.StockResponse "Stock of the item"
+// @Failure 400 {object} storage.ResponseError "Invalid item ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:read"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/items/{id}/stock [get]
+func (h *StockHandler) GetStock(c *gin.Context) {
+	itemID, err := pathID(c, "item")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	item, err := h.items.GetItem(c.Request.Context(), itemID, false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, stockResponse(item))
+}
+
+// GetStockMovements godoc
+// @Summary List the stock movements of an item
+// @Description Lists the stock ledger of an item, oldest first. Sales and returns of transactions link to the transaction; qty is the signed change of the stock
+// @Tags stock
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} storage.Page[v2.StockMovementResponse] "Page of stock movements"
+// @Failure 400 {object} storage.ResponseError "Invalid item ID or pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:read"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/items/{id}/stock-movements [get]
+func (h *StockHandler) GetStockMovements(c *gin.Context) {
+	itemID, err := pathID(c, "item")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	page, err := rest.PageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	movements, err := h.stock.GetStockMovements(c.Request.Context(), itemID, page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, rest.MapPage(movements, stockMovementResponse))
+}
+
+// AddStockMovement godoc
+// @Summary Book a stock movement
+// @Description Books a restock, return or adjustment of an item. Restocks and returns need a positive qty; adjustments a non-zero qty and a reason. Sales are booked by transactions
+// @Tags stock
+// @Accept json
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param input body v2.StockMovementRequest true "Stock movement"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v2.StockMovementResponse "Booked stock movement"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
+// @Failure 400 {object} storage.ResponseError "Invalid item ID or malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission items:write"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 409 {object} storage.ResponseError "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed, invalid movement or insufficient stock"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/items/{id}/stock-movements [post]
+func (h *StockHandler) AddStockMovement(c *gin.Context) {
+	itemID, err := pathID(c, "item")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var request StockMovementRequest
+	if err := rest.BindJSON(c, &request); err != nil {
+		c.Error(err)
+		return
+	}
+	movement := request.movement()
+	movement.ItemID = itemID
+	bookedMovement, err := h.stock.AddStockMovement(c.Request.Context(), movement)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, stockMovementResponse(bookedMovement))
+}
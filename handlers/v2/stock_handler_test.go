@@ -0,0 +1,72 @@
+package v2_test
+
+import (
+	"net/http"
+	"testing"
+
+	"lesson/storage/storagetest"
+)
+
+func TestStockMovements(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+		s := newServer(t, store)
+		s.seed("100.00", 5)
+		s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions", `{"customer_id":1,"item_id":1,"qty":2}`)
+		s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions/1/void", "")
+		s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/items/1/stock-movements", `{"kind":"adjustment","qty":-1,"reason":"stocktake"}`)
+
+		type movement struct {
+			Kind       string `json:"kind"`
+			Qty        int    `json:"qty"`
+			StockAfter int    `json:"stock_after"`
+		}
+		var movements []movement
+		path := "/v2/items/1/stock-movements?limit=3"
+		for {
+			var page struct {
+				Data       []movement `json:"data"`
+				NextCursor string     `json:"next_cursor"`
+			}
+			s.mustDo(http.StatusOK, &page, http.MethodGet, path, "")
+			movements = append(movements, page.Data...)
+			if page.NextCursor == "" {
+				break
+			}
+			path = "/v2/items/1/stock-movements?limit=3&cursor=" + page.NextCursor
+		}
+		want := []movement{
+			{Kind: "restock", Qty: 5, StockAfter: 5},
+			{Kind: "sale", Qty: -2, StockAfter: 3},
+			{Kind: "return", Qty: 2, StockAfter: 5},
+			{Kind: "adjustment", Qty: -1, StockAfter: 4},
+		}
+		if len(movements) != len(want) {
+			t.Fatalf("movements %+v, want %+v", movements, want)
+		}
+		for i := range want {
+			if movements[i] != want[i] {
+				t.Errorf("movement %d: %+v, want %+v", i, movements[i], want[i])
+			}
+		}
+		if _, stock := s.holdings(); stock != 4 {
+			t.Errorf("stock %d, want 4", stock)
+		}
+
+		tests := []struct {
+			name     string
+			body     string
+			wantCode string
+		}{
+			{name: "adjustment without a reason", body: `{"kind":"adjustment","qty":-1}`, wantCode: "invalid_stock_movement"},
+			{name: "restock that removes stock", body: `{"kind":"restock","qty":-1}`, wantCode: "invalid_stock_movement"},
+			{name: "below zero", body: `{"kind":"adjustment","qty":-5,"reason":"stocktake"}`, wantCode: "insufficient_stock"},
+			{name: "sale by hand", body: `{"kind":"sale","qty":-1}`, wantCode: "validation_failed"},
+		}
+		for _, tt := range tests {
+			rec := s.do(http.MethodPost, "/v2/items/1/stock-movements", tt.body)
+			if rec.Code != http.StatusUnprocessableEntity || errorCode(t, rec) != tt.wantCode {
+				t.Errorf("%s: status %d: %s", tt.name, rec.Code, rec.Body)
+			}
+		}
+	})
+}
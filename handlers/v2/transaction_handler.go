@@ -0,0 +1,402 @@
+package v2
+
+import (
+	"context"
+	"net/http"
+
+	"lesson/handlers/rest"
+	v1 "lesson/handlers/v1"
+	"lesson/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransactionHandler struct {
+	v1    *v1.TransactionHandler
+	store storage.TransactionStore
+}
+
+// transactionsPath is the collection voids and refunds are created in.
+const transactionsPath = "/v2/transactions"
+
+func NewTransactionHandler(store storage.TransactionStore) *TransactionHandler {
+	return &TransactionHandler{v1: v1.NewTransactionHandler(store).WithLocation(transactionsPath), store: store}
+}
+
+// GetTransactions godoc
+// @Summary List transactions
+// @Tags transactions
+// @Produce json
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param include_deleted query bool false "Include soft-deleted transactions"
+// @Success 200 {object} storage.Page[v1.TransactionResponse] "Page of transactions"
+// @Failure 400 {object} storage.ResponseError "Invalid pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/transactions [get]
+func (h *TransactionHandler) GetTransactions(c *gin.Context) {
+	h.v1.GetTransactions(c)
+}
+
+// CreateTransaction godoc
+// @Summary Create a transaction
+// @Description Records a purchase as an order of one line: the amount is computed from the item's current price and debited from the customer's balance atomically. Use /v2/orders to buy several items at once
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param input body v1.TransactionRequest true "Transaction information"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v1.TransactionResponse "Created transaction"
+// @Header 201 {string} Location "Path of the created transaction"
+// @Header 201 {string} ETag "Version of the created transaction"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
+// @Failure 400 {object} storage.ResponseError "Malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:create"
+// @Failure 409 {object} storage.ResponseError "A request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed, unknown customer or item, or insufficient balance"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/transactions [post]
+func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
+	h.v1.CreateTransaction(c)
+}
+
+// GetTransaction godoc
+// @Summary Get a transaction
+// @Tags transactions
+// @Produce json
+// @Param id path int true "Transaction ID"
+// @Param include_deleted query bool false "Return the transaction even if it is soft-deleted"
+// @Success 200 {object} v1.TransactionResponse "Transaction details"
+// @Header 200 {string} ETag "Version of the transaction, for If-Match"
+// @Failure 400 {object} storage.ResponseError "Invalid transaction ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
+// @Failure 404 {object} storage.ResponseError "Transaction not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/transactions/{id} [get]
+func (h *TransactionHandler) GetTransaction(c *gin.Context) {
+	h.v1.GetTransaction(c)
+}
+
+// UpdateTransaction godoc
+// @Summary Replace a transaction
+// @Description Replaces the qty of a sale; the amount is recomputed at the price of the sale and is read-only
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param id path int true "Transaction ID"
+// @Param If-Match header string false "ETag of the transaction as last read; the update fails with 412 if it changed since"
+// @Param input body v1.TransactionUpdateRequest true "Transaction information"
+// @Success 200 {object} v1.TransactionResponse "Updated transaction"
+// @Header 200 {string} ETag "Version of the updated transaction"
+// @Failure 400 {object} storage.ResponseError "Invalid transaction ID or malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:write"
+// @Failure 404 {object} storage.ResponseError "Transaction not found"
+// @Failure 409 {object} storage.ResponseError "Transaction was modified since the version in the body, or was voided or refunded"
+// @Failure 412 {object} storage.ResponseError "Transaction was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed, the transaction is not a sale, or the customer's balance or the item's stock does not cover a larger qty"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/transactions/{id} [put]
+func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
+	h.v1.UpdateTransaction(c)
+}
+
+// PatchTransaction godoc
+// @Summary Partially update a transaction
+// @Description Applies an RFC 7396 JSON merge patch; members that are absent keep their value. Only qty can be changed; the amount is recomputed at the price of the sale
+// @Tags transactions
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Param id path int true "Transaction ID"
+// @Param If-Match header string false "ETag of the transaction as last read; the update fails with 412 if it changed since"
+// @Param patch body v1.TransactionUpdateRequest true "Members to change"
+// @Success 200 {object} v1.TransactionResponse "Updated transaction"
+// @Header 200 {string} ETag "Version of the updated transaction"
+// @Failure 400 {object} storage.ResponseError "Invalid transaction ID or malformed patch"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:write"
+// @Failure 404 {object} storage.ResponseError "Transaction not found"
+// @Failure 409 {object} storage.ResponseError "Transaction was modified since the version in the patch, or was voided or refunded"
+// @Failure 412 {object} storage.ResponseError "Transaction was modified since the If-Match version"
+// @Failure 422 {object} storage.ResponseError "Validation failed, the transaction is not a sale, or the customer's balance or the item's stock does not cover a larger qty"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/transactions/{id} [patch]
+func (h *TransactionHandler) PatchTransaction(c *gin.Context) {
+	h.v1.PatchTransaction(c)
+}
+
+// VoidTransaction godoc
+// @Summary Void a sale
+// @Description Reverses a sale of the current day in full: records a void with a negative qty and amount, credits the customer and returns the units to stock. Sales that were partly refunded or are from an earlier day must be refunded instead
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID of the sale"
+// @Param input body v2.VoidRequest false "Why the sale is voided"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v1.TransactionResponse "The void"
+// @Header 201 {string} Location "Path of the void"
+// @Header 201 {string} ETag "Version of the void"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
+// @Failure 400 {object} storage.ResponseError "Invalid transaction ID or malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:void"
+// @Failure 404 {object} storage.ResponseError "Transaction not found"
+// @Failure 409 {object} storage.ResponseError "The sale was already voided or refunded, or a request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed, the transaction is not a sale or the sale is not from today"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/transactions/{id}/void [post]
+func (h *TransactionHandler) VoidTransaction(c *gin.Context) {
+	transactionID, err := pathID(c, "transaction")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var request VoidRequest
+	if c.Request.ContentLength != 0 {
+		if err := rest.BindJSON(c, &request); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+	void, err := h.store.VoidTransaction(c.Request.Context(), transactionID, request.Reason)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	rest.Created(c, transactionsPath, void.ID, void.Version, v1.NewTransactionResponse(void))
+}
+
+// RefundTransaction godoc
+// @Summary Refund units of a sale
+// @Description Records a refund of qty units of a sale with a negative qty and amount, credits the customer and returns the units to stock. Units are refunded at the price of the sale; a sale can be refunded in several parts up to its qty
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID of the sale"
+// @Param input body v2.RefundRequest true "Units to refund and why"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a repeat within the idempotency window returns the first response"
+// @Success 201 {object} v1.TransactionResponse "The refund"
+// @Header 201 {string} Location "Path of the refund"
+// @Header 201 {string} ETag "Version of the refund"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
+// @Failure 400 {object} storage.ResponseError "Invalid transaction ID or malformed body"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:void"
+// @Failure 404 {object} storage.ResponseError "Transaction not found"
+// @Failure 409 {object} storage.ResponseError "The sale was voided, or a request with the same Idempotency-Key is still being processed"
+// @Failure 422 {object} storage.ResponseError "Validation failed, the transaction is not a sale or qty exceeds the units not yet refunded"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/transactions/{id}/refunds [post]
+func (h *TransactionHandler) RefundTransaction(c *gin.Context) {
+	transactionID, err := pathID(c, "transaction")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var request RefundRequest
+	if err := rest.BindJSON(c, &request); err != nil {
+		c.Error(err)
+		return
+	}
+	refund, err := h.store.RefundTransaction(c.Request.Context(), transactionID, request.Qty, request.Reason)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	rest.Created(c, transactionsPath, refund.ID, refund.Version, v1.NewTransactionResponse(refund))
+}
+
+// DeleteTransaction godoc
+// @Summary Delete a transaction
+// @Description Soft deletes a transaction; it can be restored. The customer's balance and the item's stock are not touched; void or refund a sale to reverse it
+// @Tags transactions
+// @Param id path int true "Transaction ID"
+// @Success 204 "Transaction deleted"
+// @Failure 400 {object} storage.ResponseError "Invalid transaction ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:void"
+// @Failure 404 {object} storage.ResponseError "Transaction not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/transactions/{id} [delete]
+func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
+	transactionID, err := pathID(c, "transaction")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := h.store.DeleteTransaction(c.Request.Context(), transactionID); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreTransaction godoc
+// @Summary Restore a deleted transaction
+// @Tags transactions
+// @Produce json
+// @Param id path int true "Transaction ID"
+// @Success 200 {object} v1.TransactionResponse "Restored transaction"
+// @Header 200 {string} ETag "Version of the restored transaction"
+// @Failure 400 {object} storage.ResponseError "Invalid transaction ID"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:void"
+// @Failure 404 {object} storage.ResponseError "Transaction not found"
+// @Failure 409 {object} storage.ResponseError "Transaction is not deleted"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/transactions/{id}/restore [post]
+func (h *TransactionHandler) RestoreTransaction(c *gin.Context) {
+	h.v1.RestoreTransaction(c)
+}
+
+// GetCustomerTransactions godoc
+// @Summary List the transactions of a customer
+// @Tags customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param include_deleted query bool false "Include soft-deleted transactions, and answer for a soft-deleted customer"
+// @Success 200 {object} storage.Page[v1.TransactionResponse] "Page of the customer's transactions"
+// @Failure 400 {object} storage.ResponseError "Invalid customer ID or pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
+// @Failure 404 {object} storage.ResponseError "Customer not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/customers/{id}/transactions [get]
+func (h *TransactionHandler) GetCustomerTransactions(c *gin.Context) {
+	h.getOwnedTransactions(c, "customer", h.store.GetCustomerTransactions)
+}
+
+// GetItemTransactions godoc
+// @Summary List the transactions of an item
+// @Tags items
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param include_deleted query bool false "Include soft-deleted transactions, and answer for a soft-deleted item"
+// @Success 200 {object} storage.Page[v1.TransactionResponse] "Page of the item's transactions"
+// @Failure 400 {object} storage.ResponseError "Invalid item ID or pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
+// @Failure 404 {object} storage.ResponseError "Item not found"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/items/{id}/transactions [get]
+func (h *TransactionHandler) GetItemTransactions(c *gin.Context) {
+	h.getOwnedTransactions(c, "item", h.store.GetItemTransactions)
+}
+
+func (h *TransactionHandler) getOwnedTransactions(c *gin.Context, entity string, list func(ctx context.Context, id int, page storage.PageRequest, includeDeleted bool) (storage.Page[storage.Transaction], error)) {
+	ownerID, err := pathID(c, entity)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	page, err := rest.PageRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	includeDeleted, err := rest.QueryBool(c, "include_deleted")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	transactions, err := list(c.Request.Context(), ownerID, page, includeDeleted)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, rest.MapPage(transactions, v1.NewTransactionResponse))
+}
+
+// GetTransactionDetails godoc
+// @Summary List transactions with customer and item details
+// @Tags transactions
+// @Produce json
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} storage.Page[v1.TransactionViewResponse] "Page of transactions with customer and item details"
+// @Failure 400 {object} storage.ResponseError "Invalid pagination parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/transaction-details [get]
+func (h *TransactionHandler) GetTransactionDetails(c *gin.Context) {
+	h.v1.GetTransactionDetailsWithCustomerAndItem(c)
+}
+
+// SearchTransactionDetails godoc
+// @Summary Search transactions with customer and item details
+// @Description Filters by ID, customer and item, creation date, amount and quantity ranges, with sorting
+// @Tags transactions
+// @Produce json
+// @Param id query int false "Transaction ID"
+// @Param customer_id query []int false "Customer IDs, comma separated or repeated" collectionFormat(csv)
+// @Param item_id query []int false "Item IDs, comma separated or repeated" collectionFormat(csv)
+// @Param customer_name query string false "Case-insensitive partial customer name"
+// @Param item_name query string false "Case-insensitive partial item name"
+// @Param created_from query string false "Inclusive lower bound on created_at (RFC 3339 or YYYY-MM-DD)"
+// @Param created_to query string false "Exclusive upper bound on created_at (RFC 3339 or YYYY-MM-DD)"
+// @Param min_amount query string false "Minimum amount"
+// @Param max_amount query string false "Maximum amount"
+// @Param min_qty query int false "Minimum quantity"
+// @Param max_qty query int false "Maximum quantity"
+// @Param sort query string false "Sort column" Enums(id, customer_name, item_name, qty, price, amount, created_at)
+// @Param order query string false "Sort order" Enums(asc, desc)
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} storage.Page[v1.TransactionViewResponse] "Page of matching transactions"
+// @Failure 400 {object} storage.ResponseError "Invalid parameters"
+// @Failure 401 {object} storage.ResponseError "Missing or invalid credentials"
+// @Failure 403 {object} storage.ResponseError "Missing permission transactions:read"
+// @Failure 500 {object} storage.ResponseError "Internal server error"
+// @Failure 504 {object} storage.ResponseError "Database query timed out"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /v2/transaction-details/search [get]
+func (h *TransactionHandler) SearchTransactionDetails(c *gin.Context) {
+	h.v1.FilterTransactions(c)
+}
@@ -0,0 +1,284 @@
+package v2_test
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"lesson/handlers/middleware"
+	"lesson/storage/storagetest"
+)
+
+func TestCreateTransaction(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantCode    string
+		wantField   string
+		wantBalance string
+		wantStock   int
+	}{
+		{name: "debits balance and stock", body: `{"customer_id":1,"item_id":1,"qty":2}`, wantStatus: http.StatusCreated, wantBalance: "80.00", wantStock: 3},
+		{name: "insufficient balance", body: `{"customer_id":1,"item_id":1,"qty":11}`, wantStatus: http.StatusUnprocessableEntity, wantCode: "insufficient_balance", wantBalance: "100.00", wantStock: 5},
+		{name: "insufficient stock", body: `{"customer_id":1,"item_id":1,"qty":6}`, wantStatus: http.StatusUnprocessableEntity, wantCode: "insufficient_stock", wantBalance: "100.00", wantStock: 5},
+		{name: "missing quantity", body: `{"customer_id":1,"item_id":1}`, wantStatus: http.StatusUnprocessableEntity, wantCode: "validation_failed", wantField: "qty", wantBalance: "100.00", wantStock: 5},
+		{name: "unknown item", body: `{"customer_id":1,"item_id":9,"qty":1}`, wantStatus: http.StatusUnprocessableEntity, wantCode: "validation_failed", wantField: "item_id", wantBalance: "100.00", wantStock: 5},
+		{name: "read-only amount", body: `{"customer_id":1,"item_id":1,"qty":1,"amount":"1.00"}`, wantStatus: http.StatusUnprocessableEntity, wantCode: "validation_failed", wantField: "amount", wantBalance: "100.00", wantStock: 5},
+		{name: "malformed body", body: `{"customer_id":`, wantStatus: http.StatusBadRequest, wantCode: "invalid_body", wantBalance: "100.00", wantStock: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+				s := newServer(t, store)
+				s.seed("100.00", 5)
+
+				rec := s.do(http.MethodPost, "/v2/transactions", tt.body)
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+				}
+				if tt.wantCode != "" {
+					var body struct {
+						Code   string `json:"code"`
+						Fields []struct {
+							Field string `json:"field"`
+						} `json:"fields"`
+					}
+					decode(t, rec, &body)
+					if body.Code != tt.wantCode {
+						t.Errorf("code %q, want %q", body.Code, tt.wantCode)
+					}
+					if tt.wantField != "" && (len(body.Fields) != 1 || body.Fields[0].Field != tt.wantField) {
+						t.Errorf("fields %+v, want %s", body.Fields, tt.wantField)
+					}
+				} else {
+					if got := rec.Header().Get("Location"); got != "/v2/transactions/1" {
+						t.Errorf("Location %q", got)
+					}
+					if got := rec.Header().Get("ETag"); got != `"1"` {
+						t.Errorf("ETag %q", got)
+					}
+				}
+				if balance, stock := s.holdings(); balance != tt.wantBalance || stock != tt.wantStock {
+					t.Errorf("balance %s and stock %d, want %s and %d", balance, stock, tt.wantBalance, tt.wantStock)
+				}
+			})
+		})
+	}
+}
+
+func TestRefundTransaction(t *testing.T) {
+	tests := []struct {
+		name        string
+		refunds     []int
+		wantStatus  int
+		wantCode    string
+		wantBalance string
+		wantStock   int
+	}{
+		{name: "some units", refunds: []int{1}, wantStatus: http.StatusCreated, wantBalance: "80.00", wantStock: 3},
+		{name: "the remaining units", refunds: []int{1, 2}, wantStatus: http.StatusCreated, wantBalance: "100.00", wantStock: 5},
+		{name: "past the remaining quantity", refunds: []int{2, 2}, wantStatus: http.StatusUnprocessableEntity, wantCode: "refund_exceeds_sale", wantBalance: "90.00", wantStock: 4},
+		{name: "without a reason", refunds: []int{0}, wantStatus: http.StatusUnprocessableEntity, wantCode: "validation_failed", wantBalance: "70.00", wantStock: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+				s := newServer(t, store)
+				s.seed("100.00", 5)
+				s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions", `{"customer_id":1,"item_id":1,"qty":3}`)
+
+				for i, qty := range tt.refunds {
+					body := `{"qty":` + strconv.Itoa(qty) + `,"reason":"wrong size"}`
+					if qty == 0 {
+						body = `{"qty":1}`
+					}
+					rec := s.do(http.MethodPost, "/v2/transactions/1/refunds", body)
+					if i < len(tt.refunds)-1 {
+						if rec.Code != http.StatusCreated {
+							t.Fatalf("refund %d: status %d: %s", i, rec.Code, rec.Body)
+						}
+						continue
+					}
+					if rec.Code != tt.wantStatus {
+						t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+					}
+					if tt.wantCode != "" && errorCode(t, rec) != tt.wantCode {
+						t.Errorf("code %q, want %q", errorCode(t, rec), tt.wantCode)
+					}
+				}
+				if balance, stock := s.holdings(); balance != tt.wantBalance || stock != tt.wantStock {
+					t.Errorf("balance %s and stock %d, want %s and %d", balance, stock, tt.wantBalance, tt.wantStock)
+				}
+			})
+		})
+	}
+}
+
+func TestVoidTransaction(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+		s := newServer(t, store)
+		s.seed("100.00", 5)
+		s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions", `{"customer_id":1,"item_id":1,"qty":2}`)
+
+		var void struct {
+			Type                  string `json:"type"`
+			Qty                   int    `json:"qty"`
+			Amount                string `json:"amount"`
+			OriginalTransactionID int    `json:"original_transaction_id"`
+		}
+		rec := s.mustDo(http.StatusCreated, &void, http.MethodPost, "/v2/transactions/1/void", "")
+		if void.Type != "void" || void.Qty != -2 || void.Amount != "-20.00" || void.OriginalTransactionID != 1 {
+			t.Errorf("void %+v", void)
+		}
+		if got := rec.Header().Get("Location"); got != "/v2/transactions/2" {
+			t.Errorf("Location %q", got)
+		}
+		if balance, stock := s.holdings(); balance != "100.00" || stock != 5 {
+			t.Errorf("balance %s and stock %d after the void", balance, stock)
+		}
+
+		rec = s.do(http.MethodPost, "/v2/transactions/1/void", `{"reason":"again"}`)
+		if rec.Code != http.StatusConflict || errorCode(t, rec) != "transaction_reversed" {
+			t.Errorf("second void: status %d: %s", rec.Code, rec.Body)
+		}
+		rec = s.do(http.MethodPost, "/v2/transactions/2/void", "")
+		if rec.Code != http.StatusUnprocessableEntity || errorCode(t, rec) != "not_a_sale" {
+			t.Errorf("void of a void: status %d: %s", rec.Code, rec.Body)
+		}
+		if balance, stock := s.holdings(); balance != "100.00" || stock != 5 {
+			t.Errorf("balance %s and stock %d after the second void", balance, stock)
+		}
+	})
+}
+
+func TestPatchTransactionIfMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		ifMatch    string
+		wantStatus int
+		wantCode   string
+		wantETag   string
+	}{
+		{name: "current version", ifMatch: `"1"`, wantStatus: http.StatusOK, wantETag: `"2"`},
+		{name: "any version", ifMatch: "*", wantStatus: http.StatusOK, wantETag: `"2"`},
+		{name: "without If-Match", wantStatus: http.StatusOK, wantETag: `"2"`},
+		{name: "stale version", ifMatch: `"7"`, wantStatus: http.StatusPreconditionFailed, wantCode: "precondition_failed"},
+		{name: "malformed tag", ifMatch: "1", wantStatus: http.StatusBadRequest, wantCode: "invalid_if_match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+				s := newServer(t, store)
+				s.seed("100.00", 5)
+				s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions", `{"customer_id":1,"item_id":1,"qty":2}`)
+
+				var header []string
+				if tt.ifMatch != "" {
+					header = []string{"If-Match", tt.ifMatch}
+				}
+				rec := s.do(http.MethodPatch, "/v2/transactions/1", `{"qty":3}`, header...)
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+				}
+				if tt.wantCode != "" && errorCode(t, rec) != tt.wantCode {
+					t.Errorf("code %q, want %q", errorCode(t, rec), tt.wantCode)
+				}
+				if got := rec.Header().Get("ETag"); got != tt.wantETag {
+					t.Errorf("ETag %q, want %q", got, tt.wantETag)
+				}
+
+				var transaction struct {
+					Qty int `json:"qty"`
+				}
+				rec = s.mustDo(http.StatusOK, &transaction, http.MethodGet, "/v2/transactions/1", "")
+				wantQty, wantETag := 2, `"1"`
+				if tt.wantStatus == http.StatusOK {
+					wantQty, wantETag = 3, tt.wantETag
+				}
+				if transaction.Qty != wantQty || rec.Header().Get("ETag") != wantETag {
+					t.Errorf("stored qty %d with ETag %s, want %d with %s", transaction.Qty, rec.Header().Get("ETag"), wantQty, wantETag)
+				}
+			})
+		})
+	}
+}
+
+func TestCreateTransactionIdempotent(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+		s := newServer(t, store)
+		s.seed("100.00", 5)
+		const body = `{"customer_id":1,"item_id":1,"qty":2}`
+
+		first := s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions", body, middleware.IdempotencyKeyHeader, "sale-1")
+		replay := s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions", body, middleware.IdempotencyKeyHeader, "sale-1")
+		if replay.Body.String() != first.Body.String() {
+			t.Errorf("replayed body %s, want %s", replay.Body, first.Body)
+		}
+		if replay.Header().Get(middleware.IdempotentReplayedHeader) != "true" || first.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+			t.Errorf("%s: first %q, replay %q", middleware.IdempotentReplayedHeader, first.Header().Get(middleware.IdempotentReplayedHeader), replay.Header().Get(middleware.IdempotentReplayedHeader))
+		}
+		for _, name := range []string{"Location", "ETag"} {
+			if replay.Header().Get(name) != first.Header().Get(name) {
+				t.Errorf("replayed %s %q, want %q", name, replay.Header().Get(name), first.Header().Get(name))
+			}
+		}
+		if balance, stock := s.holdings(); balance != "80.00" || stock != 3 {
+			t.Errorf("balance %s and stock %d, want one sale", balance, stock)
+		}
+
+		rec := s.do(http.MethodPost, "/v2/transactions", `{"customer_id":1,"item_id":1,"qty":1}`, middleware.IdempotencyKeyHeader, "sale-1")
+		if rec.Code != http.StatusUnprocessableEntity || errorCode(t, rec) != "idempotency_key_reused" {
+			t.Errorf("reused key: status %d: %s", rec.Code, rec.Body)
+		}
+		other := s.apiKey("admin")
+		s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions", body, middleware.IdempotencyKeyHeader, "sale-1", "X-API-Key", other)
+		if balance, stock := s.holdings(); balance != "60.00" || stock != 1 {
+			t.Errorf("balance %s and stock %d, want a sale for each principal", balance, stock)
+		}
+	})
+}
+
+func TestSearchTransactionDetailsPages(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+		s := newServer(t, store)
+		s.seed("1000.00", 20)
+		for _, qty := range []string{"2", "1", "3", "1", "2"} {
+			s.mustDo(http.StatusCreated, nil, http.MethodPost, "/v2/transactions", `{"customer_id":1,"item_id":1,"qty":`+qty+`}`)
+		}
+
+		type page struct {
+			Data []struct {
+				ID  int `json:"id"`
+				Qty int `json:"qty"`
+			} `json:"data"`
+			NextCursor string `json:"next_cursor"`
+		}
+		var ids []int
+		path := "/v2/transaction-details/search?sort=qty&order=desc&limit=2&all=true"
+		for {
+			var p page
+			s.mustDo(http.StatusOK, &p, http.MethodGet, path, "")
+			if len(p.Data) > 2 {
+				t.Fatalf("page of %d rows with limit=2", len(p.Data))
+			}
+			for _, row := range p.Data {
+				ids = append(ids, row.ID)
+			}
+			if p.NextCursor == "" {
+				break
+			}
+			path = "/v2/transaction-details/search?sort=qty&order=desc&limit=2&cursor=" + p.NextCursor
+		}
+		if want := []int{3, 5, 1, 4, 2}; !slices.Equal(ids, want) {
+			t.Errorf("ids %v, want %v", ids, want)
+		}
+
+		for _, query := range []string{"limit=0", "limit=501", "cursor=bm90IGEgY3Vyc29y", "sort=reason"} {
+			if rec := s.do(http.MethodGet, "/v2/transaction-details/search?"+query, ""); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: status %d: %s", query, rec.Code, rec.Body)
+			}
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// APIKey is a credential for machine clients. Only a hash of the secret is
+// stored: the plaintext key is shown once, when the key is created. Prefix is
+// the public start of the key and is used to look it up. Role decides what
+// the key may do.
+type APIKey struct {
+	ID         int
+	Name       string
+	Role       string
+	Prefix     string
+	Hash       []byte
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// apiKeyTouchInterval is how stale last_used_at may get before TouchAPIKey
+// moves it, which spares a write per request.
+const apiKeyTouchInterval = time.Minute
+
+// ErrAPIKeyRevoked is reported when revoking a key that already is.
+var ErrAPIKeyRevoked = newError(ErrConflict, "api_key_revoked", "the API key is already revoked")
+
+const apiKeyColumns = "id, name, role, prefix, key_hash, created_at, last_used_at, revoked_at"
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (APIKey, error) {
+	var key APIKey
+	err := row.Scan(&key.ID, &key.Name, &key.Role, &key.Prefix, &key.Hash, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+	return key, err
+}
+
+func (s *PostgresStore) CreateAPIKey(ctx context.Context, key APIKey) (APIKey, error) {
+	createdKey, err := scanAPIKey(s.conn().QueryRowContext(ctx, "INSERT INTO tbl_api_key (name, role, prefix, key_hash) SELECT $1, $2, $3, $4 WHERE EXISTS (SELECT 1 FROM tbl_role WHERE name = $2) RETURNING "+apiKeyColumns,
+		key.Name, key.Role, key.Prefix, key.Hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, NotFoundError("role")
+	}
+	if err != nil {
+		return APIKey{}, translateError(err, "api_key")
+	}
+	return createdKey, nil
+}
+
+func (s *PostgresStore) GetAPIKeys(ctx context.Context, includeRevoked bool) ([]APIKey, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM tbl_api_key WHERE $1 OR revoked_at IS NULL ORDER BY id", includeRevoked)
+	if err != nil {
+		return nil, translateError(err, "api_key")
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, translateError(err, "api_key")
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, "api_key")
+	}
+	return keys, nil
+}
+
+func (s *PostgresStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (APIKey, error) {
+	key, err := scanAPIKey(s.conn().QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM tbl_api_key WHERE prefix = $1", prefix))
+	if err != nil {
+		return APIKey{}, translateError(err, "api_key")
+	}
+	return key, nil
+}
+
+func (s *PostgresStore) RevokeAPIKey(ctx context.Context, id int) (APIKey, error) {
+	revokedKey, err := scanAPIKey(s.conn().QueryRowContext(ctx, "UPDATE tbl_api_key SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL RETURNING "+apiKeyColumns, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := s.conn().QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM tbl_api_key WHERE id = $1)", id).Scan(&exists); err != nil {
+			return APIKey{}, translateError(err, "api_key")
+		}
+		if !exists {
+			return APIKey{}, NotFoundError("api_key")
+		}
+		return APIKey{}, ErrAPIKeyRevoked
+	}
+	if err != nil {
+		return APIKey{}, translateError(err, "api_key")
+	}
+	return revokedKey, nil
+}
+
+func (s *PostgresStore) TouchAPIKey(ctx context.Context, id int) error {
+	_, err := s.conn().ExecContext(ctx, "UPDATE tbl_api_key SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - $2 * INTERVAL '1 second')", id, apiKeyTouchInterval.Seconds())
+	return translateError(err, "api_key")
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds of balance entries. Purchases and refunds are posted with the
+// transaction they belong to; top-ups and adjustments are posted by hand.
+const (
+	BalanceTopUp      = "topup"
+	BalancePurchase   = "purchase"
+	BalanceRefund     = "refund"
+	BalanceAdjustment = "adjustment"
+)
+
+// BalanceEntry is an entry of the balance ledger of a customer. Amount is the
+// signed change of the balance, BalanceAfter the balance once it was posted.
+// TransactionID links purchases and refunds to their transaction.
+type BalanceEntry struct {
+	ID            int
+	CustomerID    int
+	Kind          string
+	Amount        Money
+	BalanceAfter  Money
+	TransactionID *int
+	Reason        string
+	CreatedAt     time.Time
+}
+
+// BalanceStatement lists the entries a customer's balance received from From
+// up to, but not including, To. OpeningBalance is the balance at From and
+// ClosingBalance the balance at To.
+type BalanceStatement struct {
+	CustomerID     int
+	From           time.Time
+	To             time.Time
+	OpeningBalance Money
+	Entries        []BalanceEntry
+	ClosingBalance Money
+}
+
+var ErrInvalidBalanceEntry = newError(ErrValidation, "invalid_balance_entry", "top-ups must add to the balance and adjustments need a reason")
+
+// checkBalanceEntry validates an entry posted by hand.
+func checkBalanceEntry(entry BalanceEntry) error {
+	switch entry.Kind {
+	case BalanceTopUp:
+		if entry.Amount.IsNegative() || entry.Amount.IsZero() {
+			return ErrInvalidBalanceEntry
+		}
+	case BalanceAdjustment:
+		if entry.Amount.IsZero() || entry.Reason == "" {
+			return ErrInvalidBalanceEntry
+		}
+	default:
+		return ErrInvalidBalanceEntry
+	}
+	return nil
+}
+
+// openingTopUp is the entry CreateCustomer posts for the balance a customer
+// starts with.
+func openingTopUp(customerID int, balance Money) BalanceEntry {
+	return BalanceEntry{CustomerID: customerID, Kind: BalanceTopUp, Amount: balance, Reason: "opening balance"}
+}
+
+// transactionEntry is the entry that debits the amount of a new sale, or
+// credits that of a void or refund.
+func transactionEntry(transaction Transaction) BalanceEntry {
+	id := transaction.ID
+	kind := BalancePurchase
+	if transaction.Type != TransactionSale {
+		kind = BalanceRefund
+	}
+	return BalanceEntry{CustomerID: transaction.CustomerID, Kind: kind, Amount: Money{}.Sub(transaction.Amount), TransactionID: &id, Reason: transaction.Reason}
+}
+
+// saleChangeEntry is the entry that debits the customer for what a changed
+// sale costs more than oldAmount, or credits what it costs less.
+func saleChangeEntry(sale Transaction, oldAmount Money) BalanceEntry {
+	id := sale.ID
+	amount := oldAmount.Sub(sale.Amount)
+	kind := BalancePurchase
+	if !amount.IsNegative() {
+		kind = BalanceRefund
+	}
+	return BalanceEntry{CustomerID: sale.CustomerID, Kind: kind, Amount: amount, TransactionID: &id, Reason: "sale changed"}
+}
+
+const balanceEntryColumns = "id, customer_id, kind, amount, balance_after, transaction_id, reason, created_at"
+
+func scanBalanceEntry(row interface{ Scan(...interface{}) error }) (BalanceEntry, error) {
+	var entry BalanceEntry
+	err := row.Scan(&entry.ID, &entry.CustomerID, &entry.Kind, &entry.Amount, &entry.BalanceAfter, &entry.TransactionID, &entry.Reason, &entry.CreatedAt)
+	return entry, err
+}
+
+// postBalance applies entry to the balance of a live customer and books it in
+// the ledger. It is the only way a balance changes. Taking the balance below
+// zero fails with ErrInsufficientBalance. q must be a transaction, which holds
+// the lock on the customer until it ends.
+func postBalance(ctx context.Context, q queryer, entry BalanceEntry) (BalanceEntry, error) {
+	var balance Money
+	err := q.QueryRowContext(ctx, "SELECT balance FROM tbl_customer WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", entry.CustomerID).
+		Scan(&balance)
+	if err != nil {
+		return BalanceEntry{}, translateError(err, "customer")
+	}
+	balanceAfter := balance.Add(entry.Amount)
+	if balanceAfter.IsNegative() {
+		return BalanceEntry{}, ErrInsufficientBalance
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE tbl_customer SET balance = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", balanceAfter, entry.CustomerID); err != nil {
+		return BalanceEntry{}, translateError(err, "customer")
+	}
+	postedEntry, err := scanBalanceEntry(q.QueryRowContext(ctx, "INSERT INTO tbl_balance_entries (customer_id, kind, amount, balance_after, transaction_id, reason) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+balanceEntryColumns,
+		entry.CustomerID, entry.Kind, entry.Amount, balanceAfter, entry.TransactionID, entry.Reason))
+	if err != nil {
+		return BalanceEntry{}, translateError(err, "balance_entry")
+	}
+	return postedEntry, nil
+}
+
+func (s *PostgresStore) AddBalanceEntry(ctx context.Context, entry BalanceEntry) (BalanceEntry, error) {
+	if err := checkBalanceEntry(entry); err != nil {
+		return BalanceEntry{}, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return BalanceEntry{}, translateError(err, "balance_entry")
+	}
+	defer tx.Rollback()
+
+	entry.TransactionID = nil
+	postedEntry, err := postBalance(ctx, s.inTx(tx), entry)
+	if err != nil {
+		return BalanceEntry{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return BalanceEntry{}, translateError(err, "balance_entry")
+	}
+	return postedEntry, nil
+}
+
+func (s *PostgresStore) GetBalanceStatement(ctx context.Context, customerID int, from, to time.Time) (BalanceStatement, error) {
+	var exists bool
+	if err := s.conn().QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM tbl_customer WHERE id = $1)", customerID).Scan(&exists); err != nil {
+		return BalanceStatement{}, translateError(err, "customer")
+	}
+	if !exists {
+		return BalanceStatement{}, NotFoundError("customer")
+	}
+
+	statement := BalanceStatement{CustomerID: customerID, From: from, To: to}
+	err := s.conn().QueryRowContext(ctx, "SELECT COALESCE((SELECT balance_after FROM tbl_balance_entries WHERE customer_id = $1 AND created_at < $2 ORDER BY created_at DESC, id DESC LIMIT 1), 0)", customerID, from).
+		Scan(&statement.OpeningBalance)
+	if err != nil {
+		return BalanceStatement{}, translateError(err, "balance_entry")
+	}
+
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+balanceEntryColumns+" FROM tbl_balance_entries WHERE customer_id = $1 AND created_at >= $2 AND created_at < $3 ORDER BY created_at, id", customerID, from, to)
+	if err != nil {
+		return BalanceStatement{}, translateError(err, "balance_entry")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanBalanceEntry(rows)
+		if err != nil {
+			return BalanceStatement{}, translateError(err, "balance_entry")
+		}
+		statement.Entries = append(statement.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return BalanceStatement{}, translateError(err, "balance_entry")
+	}
+
+	statement.ClosingBalance = statement.OpeningBalance
+	if n := len(statement.Entries); n > 0 {
+		statement.ClosingBalance = statement.Entries[n-1].BalanceAfter
+	}
+	return statement, nil
+}
@@ -1,78 +1,171 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"time"
 )
 
+// Customer is a row of tbl_customer. Balance only changes through entries of
+// the balance ledger; see BalanceStore.
 type Customer struct {
-	ID        int     `json:"id"`
-	Name      string  `json:"customer_name"`
-	Balance   float64 `json:"balance"`
-	CreatedAt string  `json:"created_at"`
-	UpdatedAt string  `json:"updated_at"`
-	DeletedAt string  `json:"deleted_at"`
+	ID        int
+	Name      string
+	Balance   Money
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+	Version   int
 }
 
+// CustomerPatch lists the columns a partial update changes; nil fields keep
+// their stored value. A non-zero Version must match the stored version.
+type CustomerPatch struct {
+	Name    *string
+	Version int
+}
+
+// ResponseError is the body of every error response. Code is a stable,
+// machine-readable identifier such as "customer_not_found". Fields lists the
+// invalid request members of a validation error.
 type ResponseError struct {
-	Error interface{} `json:"error"`
-	Code  int         `json:"code"`
+	Error  string       `json:"error" example:"customer not found"`
+	Code   string       `json:"code" example:"customer_not_found"`
+	Fields []FieldError `json:"fields,omitempty"`
 }
 
-func CreateCustomer(db *sql.DB, customer Customer) (Customer, error) {
-	var createdCustomer Customer
-	err := db.QueryRow("INSERT INTO tbl_customer (customer_name, balance) VALUES ($1, $2) RETURNING id, customer_name, balance, created_at, updated_at", customer.Name, customer.Balance).
-		Scan(&createdCustomer.ID, &createdCustomer.Name, &createdCustomer.Balance, &createdCustomer.CreatedAt, &createdCustomer.UpdatedAt)
+// customerColumns are scanned by scanCustomer. A row that was never updated
+// reports its creation time as updated_at.
+const customerColumns = "id, customer_name, balance, created_at, COALESCE(updated_at, created_at), deleted_at, version"
+
+func scanCustomer(row interface{ Scan(...interface{}) error }) (Customer, error) {
+	var customer Customer
+	err := row.Scan(&customer.ID, &customer.Name, &customer.Balance, &customer.CreatedAt, &customer.UpdatedAt, &customer.DeletedAt, &customer.Version)
+	return customer, err
+}
+
+// CreateCustomer inserts a customer. A non-zero customer.Balance is posted as
+// a top-up in the same database transaction.
+func (s *PostgresStore) CreateCustomer(ctx context.Context, customer Customer) (Customer, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Customer{}, translateError(err, "customer")
+	}
+	defer tx.Rollback()
+
+	createdCustomer, err := scanCustomer(s.inTx(tx).QueryRowContext(ctx, "INSERT INTO tbl_customer (customer_name, balance) VALUES ($1, 0) RETURNING "+customerColumns, customer.Name))
 	if err != nil {
-		return Customer{}, err
+		return Customer{}, translateError(err, "customer")
+	}
+	if !customer.Balance.IsZero() {
+		if _, err := postBalance(ctx, s.inTx(tx), openingTopUp(createdCustomer.ID, customer.Balance)); err != nil {
+			return Customer{}, err
+		}
+		createdCustomer, err = scanCustomer(s.inTx(tx).QueryRowContext(ctx, "SELECT "+customerColumns+" FROM tbl_customer WHERE id = $1", createdCustomer.ID))
+		if err != nil {
+			return Customer{}, translateError(err, "customer")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Customer{}, translateError(err, "customer")
 	}
 	return createdCustomer, nil
 }
 
-func UpdateCustomer(db *sql.DB, customer Customer) (Customer, error) {
-	_, err := db.Exec("UPDATE tbl_customer SET customer_name = $1, balance = $2 WHERE id = $3", customer.Name, customer.Balance, customer.ID)
+// UpdateCustomer overwrites the name of a live customer and returns the
+// updated row; customer.Balance is ignored. A non-zero customer.Version must
+// match the stored version, otherwise the update reports StaleVersionError.
+func (s *PostgresStore) UpdateCustomer(ctx context.Context, customer Customer) (Customer, error) {
+	updatedCustomer, err := scanCustomer(s.conn().QueryRowContext(ctx, "UPDATE tbl_customer SET customer_name = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3) RETURNING "+customerColumns,
+		customer.Name, customer.ID, customer.Version))
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.GetCustomer(ctx, customer.ID, false); err != nil {
+			return Customer{}, err
+		}
+		return Customer{}, StaleVersionError("customer")
+	}
 	if err != nil {
-		return Customer{}, err
+		return Customer{}, translateError(err, "customer")
 	}
-	return customer, nil
+	return updatedCustomer, nil
 }
 
-func DeleteCustomer(db *sql.DB, id int) (Customer, error) {
-	var deletedCustomer Customer
-	err := db.QueryRow("DELETE FROM tbl_customer WHERE id = $1 RETURNING id, customer_name, balance, created_at, updated_at", id).
-		Scan(&deletedCustomer.ID, &deletedCustomer.Name, &deletedCustomer.Balance, &deletedCustomer.CreatedAt, &deletedCustomer.UpdatedAt)
+// PatchCustomer applies patch to a live customer and returns the updated row.
+func (s *PostgresStore) PatchCustomer(ctx context.Context, id int, patch CustomerPatch) (Customer, error) {
+	patchedCustomer, err := scanCustomer(s.conn().QueryRowContext(ctx, "UPDATE tbl_customer SET customer_name = COALESCE($1, customer_name), version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3) RETURNING "+customerColumns,
+		patch.Name, id, patch.Version))
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.GetCustomer(ctx, id, false); err != nil {
+			return Customer{}, err
+		}
+		return Customer{}, StaleVersionError("customer")
+	}
 	if err != nil {
-		return Customer{}, err
+		return Customer{}, translateError(err, "customer")
+	}
+	return patchedCustomer, nil
+}
+
+// DeleteCustomer soft-deletes a customer. Deleting a missing or already
+// deleted customer reports not found.
+func (s *PostgresStore) DeleteCustomer(ctx context.Context, id int) (Customer, error) {
+	deletedCustomer, err := scanCustomer(s.conn().QueryRowContext(ctx, "UPDATE tbl_customer SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL RETURNING "+customerColumns, id))
+	if err != nil {
+		return Customer{}, translateError(err, "customer")
 	}
 	return deletedCustomer, nil
 }
 
-func GetCustomer(db *sql.DB, id int) (Customer, error) {
-	var customer Customer
-	err := db.QueryRow("SELECT id, customer_name, balance, created_at, updated_at FROM tbl_customer WHERE id = $1", id).
-		Scan(&customer.ID, &customer.Name, &customer.Balance, &customer.CreatedAt, &customer.UpdatedAt)
+// RestoreCustomer undoes DeleteCustomer. Restoring a customer that is not
+// deleted is a conflict.
+func (s *PostgresStore) RestoreCustomer(ctx context.Context, id int) (Customer, error) {
+	restoredCustomer, err := scanCustomer(s.conn().QueryRowContext(ctx, "UPDATE tbl_customer SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL RETURNING "+customerColumns, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.GetCustomer(ctx, id, false); err != nil {
+			return Customer{}, err
+		}
+		return Customer{}, NotDeletedError("customer")
+	}
 	if err != nil {
-		return Customer{}, err
+		return Customer{}, translateError(err, "customer")
+	}
+	return restoredCustomer, nil
+}
+
+func (s *PostgresStore) GetCustomer(ctx context.Context, id int, includeDeleted bool) (Customer, error) {
+	customer, err := scanCustomer(s.conn().QueryRowContext(ctx, "SELECT "+customerColumns+" FROM tbl_customer WHERE id = $1 AND ($2 OR deleted_at IS NULL)", id, includeDeleted))
+	if err != nil {
+		return Customer{}, translateError(err, "customer")
 	}
 	return customer, nil
 }
 
-func GetCustomers(db *sql.DB) ([]Customer, error) {
-	rows, err := db.Query("SELECT id, customer_name, balance, created_at, updated_at FROM tbl_customer WHERE deleted_at IS NULL")
+func (s *PostgresStore) GetCustomers(ctx context.Context, page PageRequest, includeDeleted bool) (Page[Customer], error) {
+	after, err := decodeCursor(page.Cursor)
 	if err != nil {
-		return nil, err
+		return Page[Customer]{}, err
+	}
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+customerColumns+" FROM tbl_customer WHERE ($1 OR deleted_at IS NULL) AND id > $2 ORDER BY id LIMIT $3", includeDeleted, after.ID, page.limitClause())
+	if err != nil {
+		return Page[Customer]{}, translateError(err, "customer")
 	}
 	defer rows.Close()
 
 	var customers []Customer
 
 	for rows.Next() {
-		var customer Customer
-		err := rows.Scan(&customer.ID, &customer.Name, &customer.Balance, &customer.CreatedAt, &customer.UpdatedAt)
+		customer, err := scanCustomer(rows)
 		if err != nil {
-			return nil, err
+			return Page[Customer]{}, translateError(err, "customer")
 		}
 		customers = append(customers, customer)
 	}
 
-	return customers, nil
+	if err := rows.Err(); err != nil {
+		return Page[Customer]{}, translateError(err, "customer")
+	}
+
+	return newPage(customers, page, func(c Customer) cursor { return cursor{ID: c.ID} }), nil
 }
@@ -1,31 +1,66 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"log"
+	"log/slog"
+	"time"
 
-	_ "github.com/lib/pq"
-)
+	"lesson/config"
 
-const (
-	host     = "localhost"
-	port     = 5432
-	user     = "postgres"
-	password = "nodirbek"
-	dbname   = "postgres"
+	_ "github.com/lib/pq"
 )
 
-func InitDB() (*sql.DB, error) {
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
-	db, err := sql.Open("postgres", psqlInfo)
+func InitDB(cfg config.DB) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.DSN())
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime.Duration())
+
 	err = db.Ping()
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 	log.Println("Connected to PostgreSQL database")
 	return db, nil
 }
+
+// queryer is the part of *sql.DB and *sql.Tx the stores use.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// slowQueryLogger logs statements that take longer than threshold. Only the
+// statement text is logged, never its arguments.
+type slowQueryLogger struct {
+	q         queryer
+	threshold time.Duration
+}
+
+func (l slowQueryLogger) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer l.observe(time.Now(), query)
+	return l.q.ExecContext(ctx, query, args...)
+}
+
+func (l slowQueryLogger) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer l.observe(time.Now(), query)
+	return l.q.QueryContext(ctx, query, args...)
+}
+
+func (l slowQueryLogger) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer l.observe(time.Now(), query)
+	return l.q.QueryRowContext(ctx, query, args...)
+}
+
+func (l slowQueryLogger) observe(start time.Time, query string) {
+	if elapsed := time.Since(start); l.threshold > 0 && elapsed >= l.threshold {
+		slog.Warn("slow query", "duration", elapsed, "query", query)
+	}
+}
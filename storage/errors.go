@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Error kinds. Every *Error wraps exactly one of them, so callers can test
+// the category with errors.Is and map it to a response status.
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrConstraint      = errors.New("constraint violation")
+	ErrValidation      = errors.New("validation failed")
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrTimeout         = errors.New("timeout")
+	ErrCanceled        = errors.New("canceled")
+	ErrPrecondition    = errors.New("precondition failed")
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// ErrStaleVersion is wrapped by the conflict an update reports when the row
+// is no longer at the version the caller read.
+var ErrStaleVersion = errors.New("stale version")
+
+// Error is a domain error. Code is a stable, machine-readable identifier such
+// as "customer_not_found"; Message is safe to show to API clients and never
+// contains driver output.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+// FieldError describes one invalid member of a request body. Rule names the
+// check that failed, e.g. "required", "gte" or "read_only".
+type FieldError struct {
+	Field   string `json:"field" example:"customer_name"`
+	Rule    string `json:"rule" example:"required"`
+	Message string `json:"message" example:"customer_name is required"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+func newError(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// NotFoundError reports a missing row of the given entity, e.g. "customer".
+func NotFoundError(entity string) *Error {
+	return newError(ErrNotFound, entity+"_not_found", entity+" not found")
+}
+
+// NotDeletedError reports an attempt to restore a row that is not deleted.
+func NotDeletedError(entity string) *Error {
+	return newError(ErrConflict, entity+"_not_deleted", entity+" is not deleted")
+}
+
+// StaleVersionError reports an update of a row that changed since the caller
+// read it.
+func StaleVersionError(entity string) *Error {
+	e := newError(ErrConflict, entity+"_version_conflict", entity+" was modified by another request")
+	e.Err = ErrStaleVersion
+	return e
+}
+
+// PreconditionFailedError reports a conditional request whose If-Match no
+// longer matches the row.
+func PreconditionFailedError(entity string) *Error {
+	e := newError(ErrPrecondition, "precondition_failed", entity+" does not match If-Match")
+	e.Err = ErrStaleVersion
+	return e
+}
+
+// ValidationError reports a request body that failed validation.
+func ValidationError(fields []FieldError) *Error {
+	e := newError(ErrValidation, "validation_failed", "request validation failed")
+	e.Fields = fields
+	return e
+}
+
+// UnauthenticatedError reports a request without valid credentials. message
+// says what was wrong without revealing whether a key or subject exists.
+func UnauthenticatedError(message string) *Error {
+	return newError(ErrUnauthenticated, "unauthenticated", message)
+}
+
+// ForbiddenError reports an authenticated caller lacking permission, which
+// the message names so that an administrator knows which role to grant.
+func ForbiddenError(permission string) *Error {
+	return newError(ErrForbidden, "forbidden", "permission "+permission+" is required")
+}
+
+// InvalidArgumentError reports a malformed request parameter.
+func InvalidArgumentError(code, message string) *Error {
+	return newError(ErrInvalidArgument, code, message)
+}
+
+// translateError turns database/sql and PostgreSQL errors into domain errors.
+// entity names the row the statement was about and is used for not found
+// errors. Unknown errors are returned unchanged and end up as 500s.
+func translateError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		e := NotFoundError(entity)
+		e.Err = err
+		return e
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return timeoutError(err)
+	}
+	if errors.Is(err, context.Canceled) {
+		e := newError(ErrCanceled, "request_canceled", "the request was canceled")
+		e.Err = err
+		return e
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	var e *Error
+	switch pqErr.Code.Name() {
+	case "foreign_key_violation":
+		e = newError(ErrConstraint, "foreign_key_violation", referenceMessage(pqErr, entity))
+	case "unique_violation":
+		e = newError(ErrConflict, "unique_violation", fmt.Sprintf("%s already exists", entity))
+	case "not_null_violation":
+		e = newError(ErrValidation, "not_null_violation", fmt.Sprintf("%s is required", pqErr.Column))
+	case "check_violation":
+		e = newError(ErrConstraint, "check_violation", fmt.Sprintf("%s violates constraint %s", entity, pqErr.Constraint))
+	case "query_canceled":
+		// The driver cancels a statement when its context ends. Clients that
+		// went away never see the response, so report it as a timeout.
+		return timeoutError(err)
+	case "serialization_failure", "deadlock_detected":
+		e = newError(ErrConflict, "concurrent_update", "the row was modified concurrently, retry the request")
+	default:
+		if pqErr.Code.Class() == "22" {
+			e = newError(ErrValidation, "invalid_value", "a value is out of range or malformed")
+		} else {
+			return err
+		}
+	}
+	e.Err = err
+	return e
+}
+
+func timeoutError(err error) *Error {
+	e := newError(ErrTimeout, "query_timeout", "the database did not respond in time")
+	e.Err = err
+	return e
+}
+
+// referenceMessage explains a foreign key violation on tbl_transaction. When
+// entity is the referenced side the row is still in use, otherwise the
+// referenced row is missing.
+func referenceMessage(pqErr *pq.Error, entity string) string {
+	var referenced string
+	switch pqErr.Constraint {
+	case "tbl_transaction_customer_id_fkey":
+		referenced = "customer"
+	case "tbl_transaction_item_id_fkey":
+		referenced = "item"
+	default:
+		return "the row references a missing row or is still referenced"
+	}
+	if entity == referenced {
+		return referenced + " is still referenced by transactions"
+	}
+	return referenced + " does not exist"
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// IdempotencyRecord remembers the response to a request sent with an
+// Idempotency-Key, so that a retry gets the same response instead of
+// repeating the request. Keys belong to the principal that used them.
+// Status is zero while the first request is still being processed.
+type IdempotencyRecord struct {
+	Principal   string
+	Key         string
+	RequestHash []byte
+	Status      int
+	Header      map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// ErrIdempotencyKeyReused is reported when a key is sent again with a
+// different request.
+var ErrIdempotencyKeyReused = newError(ErrValidation, "idempotency_key_reused", "the Idempotency-Key was already used for a different request")
+
+// ErrIdempotencyKeyInUse is reported when a key is sent again while the
+// first request with it is still being processed.
+var ErrIdempotencyKeyInUse = newError(ErrConflict, "idempotency_key_in_use", "a request with this Idempotency-Key is still being processed, retry later")
+
+func (s *PostgresStore) ReserveIdempotencyKey(ctx context.Context, record IdempotencyRecord, ttl time.Duration) (IdempotencyRecord, bool, error) {
+	// An expired record that was not cleaned up yet is taken over.
+	var createdAt, expiresAt time.Time
+	err := s.conn().QueryRowContext(ctx, `INSERT INTO tbl_idempotency_key (principal, idempotency_key, request_hash, expires_at) VALUES ($1, $2, $3, CURRENT_TIMESTAMP + $4 * INTERVAL '1 second')
+		ON CONFLICT (principal, idempotency_key) DO UPDATE SET request_hash = EXCLUDED.request_hash, status = NULL, headers = NULL, body = NULL, created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+		WHERE tbl_idempotency_key.expires_at <= CURRENT_TIMESTAMP
+		RETURNING created_at, expires_at`,
+		record.Principal, record.Key, record.RequestHash, ttl.Seconds()).Scan(&createdAt, &expiresAt)
+	if err == nil {
+		record.CreatedAt, record.ExpiresAt = createdAt, expiresAt
+		return record, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return IdempotencyRecord{}, false, translateError(err, "idempotency_key")
+	}
+
+	existing := IdempotencyRecord{Principal: record.Principal, Key: record.Key}
+	var status sql.NullInt64
+	var header []byte
+	err = s.conn().QueryRowContext(ctx, "SELECT request_hash, status, headers, body, created_at, expires_at FROM tbl_idempotency_key WHERE principal = $1 AND idempotency_key = $2",
+		record.Principal, record.Key).Scan(&existing.RequestHash, &status, &header, &existing.Body, &existing.CreatedAt, &existing.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The first request failed and released the key in the meantime.
+		return IdempotencyRecord{}, false, ErrIdempotencyKeyInUse
+	}
+	if err != nil {
+		return IdempotencyRecord{}, false, translateError(err, "idempotency_key")
+	}
+	existing.Status = int(status.Int64)
+	if header != nil {
+		if err := json.Unmarshal(header, &existing.Header); err != nil {
+			return IdempotencyRecord{}, false, err
+		}
+	}
+	return existing, false, nil
+}
+
+func (s *PostgresStore) CompleteIdempotencyKey(ctx context.Context, record IdempotencyRecord) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn().ExecContext(ctx, "UPDATE tbl_idempotency_key SET status = $1, headers = $2, body = $3 WHERE principal = $4 AND idempotency_key = $5",
+		record.Status, header, record.Body, record.Principal, record.Key)
+	return translateError(err, "idempotency_key")
+}
+
+func (s *PostgresStore) ReleaseIdempotencyKey(ctx context.Context, principal, key string) error {
+	_, err := s.conn().ExecContext(ctx, "DELETE FROM tbl_idempotency_key WHERE principal = $1 AND idempotency_key = $2 AND status IS NULL", principal, key)
+	return translateError(err, "idempotency_key")
+}
+
+func (s *PostgresStore) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	result, err := s.conn().ExecContext(ctx, "DELETE FROM tbl_idempotency_key WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, translateError(err, "idempotency_key")
+	}
+	return result.RowsAffected()
+}
@@ -1,75 +1,160 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 )
 
+// Item is a row of tbl_items. Stock is the quantity on hand; it only changes
+// through stock movements, which do not bump Version.
 type Item struct {
-	ID        int     `json:"id"`
-	Name      string  `json:"item_name"`
-	Cost      float64 `json:"cost"`
-	Price     float64 `json:"price"`
-	Sort      int     `json:"sort"`
-	CreatedAt string  `json:"created_at"`
-	UpdatedAt string  `json:"updated_at"`
-	DeletedAt string  `json:"deleted_at"`
+	ID             int
+	Name           string
+	Cost           Money
+	Price          Money
+	Sort           int
+	Stock          int
+	AllowBackorder bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      *time.Time
+	Version        int
 }
 
-func CreateItem(db *sql.DB, item Item) (Item, error) {
-	var createdItem Item
-	err := db.QueryRow("INSERT INTO tbl_items (item_name, cost, price, sort) VALUES ($1, $2, $3, $4) RETURNING id, item_name, cost, price, sort, created_at", item.Name, item.Cost, item.Price, item.Sort).
-		Scan(&createdItem.ID, &createdItem.Name, &createdItem.Cost, &createdItem.Price, &createdItem.Sort, &createdItem.CreatedAt)
+// ItemPatch lists the columns a partial update changes; nil fields keep their
+// stored value. A non-zero Version must match the stored version.
+type ItemPatch struct {
+	Name           *string
+	Cost           *Money
+	Price          *Money
+	Sort           *int
+	AllowBackorder *bool
+	Version        int
+}
+
+// itemColumns are scanned by scanItem. A row that was never updated reports
+// its creation time as updated_at.
+const itemColumns = "id, item_name, cost, price, COALESCE(sort, 0), stock, allow_backorder, created_at, COALESCE(updated_at, created_at), deleted_at, version"
+
+func scanItem(row interface{ Scan(...interface{}) error }) (Item, error) {
+	var item Item
+	err := row.Scan(&item.ID, &item.Name, &item.Cost, &item.Price, &item.Sort, &item.Stock, &item.AllowBackorder, &item.CreatedAt, &item.UpdatedAt, &item.DeletedAt, &item.Version)
+	return item, err
+}
+
+func (s *PostgresStore) CreateItem(ctx context.Context, item Item) (Item, error) {
+	createdItem, err := scanItem(s.conn().QueryRowContext(ctx, "INSERT INTO tbl_items (item_name, cost, price, sort, allow_backorder) VALUES ($1, $2, $3, $4, $5) RETURNING "+itemColumns, item.Name, item.Cost, item.Price, item.Sort, item.AllowBackorder))
 	if err != nil {
-		return Item{}, err
+		return Item{}, translateError(err, "item")
 	}
 	return createdItem, nil
 }
 
-func UpdateItem(db *sql.DB, item Item) (Item, error) {
-	_, err := db.Exec("UPDATE tbl_items SET item_name = $1, cost = $2, price = $3, sort = $4 WHERE id = $5", item.Name, item.Cost, item.Price, item.Sort, item.ID)
+// UpdateItem overwrites a live item and returns the updated row. A non-zero
+// item.Version must match the stored version, otherwise the update reports
+// StaleVersionError.
+func (s *PostgresStore) UpdateItem(ctx context.Context, item Item) (Item, error) {
+	updatedItem, err := scanItem(s.conn().QueryRowContext(ctx, "UPDATE tbl_items SET item_name = $1, cost = $2, price = $3, sort = $4, allow_backorder = $5, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $6 AND deleted_at IS NULL AND ($7 = 0 OR version = $7) RETURNING "+itemColumns,
+		item.Name, item.Cost, item.Price, item.Sort, item.AllowBackorder, item.ID, item.Version))
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.GetItem(ctx, item.ID, false); err != nil {
+			return Item{}, err
+		}
+		return Item{}, StaleVersionError("item")
+	}
 	if err != nil {
-		return Item{}, err
+		return Item{}, translateError(err, "item")
 	}
-	return item, nil
+	return updatedItem, nil
+}
+
+// PatchItem applies patch to a live item and returns the updated row.
+func (s *PostgresStore) PatchItem(ctx context.Context, id int, patch ItemPatch) (Item, error) {
+	patchedItem, err := scanItem(s.conn().QueryRowContext(ctx, "UPDATE tbl_items SET item_name = COALESCE($1, item_name), cost = COALESCE($2, cost), price = COALESCE($3, price), sort = COALESCE($4, sort), allow_backorder = COALESCE($5, allow_backorder), version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $6 AND deleted_at IS NULL AND ($7 = 0 OR version = $7) RETURNING "+itemColumns,
+		patch.Name, patch.Cost, patch.Price, patch.Sort, patch.AllowBackorder, id, patch.Version))
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.GetItem(ctx, id, false); err != nil {
+			return Item{}, err
+		}
+		return Item{}, StaleVersionError("item")
+	}
+	if err != nil {
+		return Item{}, translateError(err, "item")
+	}
+	return patchedItem, nil
 }
 
-func DeleteItem(db *sql.DB, id int) (Item, error) {
-	var deletedItem Item
-	err := db.QueryRow("DELETE FROM tbl_items WHERE id = $1 RETURNING id, item_name, cost, price, sort, created_at", id).
-		Scan(&deletedItem.ID, &deletedItem.Name, &deletedItem.Cost, &deletedItem.Price, &deletedItem.Sort, &deletedItem.CreatedAt)
+// DeleteItem soft-deletes an item. Deleting a missing or already deleted item
+// reports not found.
+func (s *PostgresStore) DeleteItem(ctx context.Context, id int) (Item, error) {
+	deletedItem, err := scanItem(s.conn().QueryRowContext(ctx, "UPDATE tbl_items SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL RETURNING "+itemColumns, id))
 	if err != nil {
-		return Item{}, err
+		return Item{}, translateError(err, "item")
 	}
 	return deletedItem, nil
 }
 
-func GetItem(db *sql.DB, id int) (Item, error) {
-	var item Item
-	err := db.QueryRow("SELECT id, item_name, cost, price, sort, created_at FROM tbl_items WHERE id = $1", id).
-		Scan(&item.ID, &item.Name, &item.Cost, &item.Price, &item.Sort, &item.CreatedAt)
+// RestoreItem undoes DeleteItem. Restoring an item that is not deleted is a
+// conflict.
+func (s *PostgresStore) RestoreItem(ctx context.Context, id int) (Item, error) {
+	restoredItem, err := scanItem(s.conn().QueryRowContext(ctx, "UPDATE tbl_items SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL RETURNING "+itemColumns, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.GetItem(ctx, id, false); err != nil {
+			return Item{}, err
+		}
+		return Item{}, NotDeletedError("item")
+	}
+	if err != nil {
+		return Item{}, translateError(err, "item")
+	}
+	return restoredItem, nil
+}
+
+func (s *PostgresStore) GetItem(ctx context.Context, id int, includeDeleted bool) (Item, error) {
+	item, err := scanItem(s.conn().QueryRowContext(ctx, "SELECT "+itemColumns+" FROM tbl_items WHERE id = $1 AND ($2 OR deleted_at IS NULL)", id, includeDeleted))
 	if err != nil {
-		return Item{}, err
+		return Item{}, translateError(err, "item")
 	}
 	return item, nil
 }
 
-func GetItems(db *sql.DB) ([]Item, error) {
-	rows, err := db.Query("SELECT id, item_name, cost, price, sort, created_at, updated_at FROM tbl_items WHERE deleted_at IS NULL")
+// GetItems lists items ordered by sort and then by id.
+func (s *PostgresStore) GetItems(ctx context.Context, page PageRequest, includeDeleted bool) (Page[Item], error) {
+	after, err := decodeCursor(page.Cursor)
 	if err != nil {
-		return nil, err
+		return Page[Item]{}, translateError(err, "item")
+	}
+	query := "SELECT " + itemColumns + " FROM tbl_items WHERE ($1 OR deleted_at IS NULL)"
+	args := []interface{}{includeDeleted}
+	if page.Cursor != "" {
+		query += " AND (COALESCE(sort, 0), id) > ($2, $3)"
+		args = append(args, after.Sort, after.ID)
+	}
+	args = append(args, page.limitClause())
+	query += fmt.Sprintf(" ORDER BY COALESCE(sort, 0), id LIMIT $%d", len(args))
+
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return Page[Item]{}, translateError(err, "item")
 	}
 	defer rows.Close()
 
 	var items []Item
 
 	for rows.Next() {
-		var item Item
-		err := rows.Scan(&item.ID, &item.Name, &item.Cost, &item.Price, &item.Sort, &item.CreatedAt, &item.UpdatedAt)
+		item, err := scanItem(rows)
 		if err != nil {
-			return nil, err
+			return Page[Item]{}, translateError(err, "item")
 		}
 		items = append(items, item)
 	}
 
-	return items, nil
+	if err := rows.Err(); err != nil {
+		return Page[Item]{}, translateError(err, "item")
+	}
+
+	return newPage(items, page, func(i Item) cursor { return cursor{ID: i.ID, Sort: i.Sort} }), nil
 }
@@ -0,0 +1,1099 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryTransaction struct {
+	Transaction
+	price Money
+}
+
+// MemoryStore implements CustomerStore, ItemStore, TransactionStore,
+// OrderStore, BalanceStore, StockStore, APIKeyStore, RoleStore and
+// IdempotencyStore in process memory. It mirrors the behaviour of
+// PostgresStore, including the domain errors it returns, and is meant for
+// tests and demos.
+type MemoryStore struct {
+	mu sync.Mutex
+
+	customers    map[int]Customer
+	items        map[int]Item
+	transactions map[int]memoryTransaction
+	orders       map[int]Order
+	entries      []BalanceEntry
+	movements    []StockMovement
+	apiKeys      map[int]APIKey
+	idempotency  map[[2]string]IdempotencyRecord
+
+	nextCustomerID    int
+	nextItemID        int
+	nextTransactionID int
+	nextOrderID       int
+	nextAPIKeyID      int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		customers:    make(map[int]Customer),
+		items:        make(map[int]Item),
+		transactions: make(map[int]memoryTransaction),
+		orders:       make(map[int]Order),
+		apiKeys:      make(map[int]APIKey),
+		idempotency:  make(map[[2]string]IdempotencyRecord),
+	}
+}
+
+var (
+	_ CustomerStore    = (*MemoryStore)(nil)
+	_ ItemStore        = (*MemoryStore)(nil)
+	_ TransactionStore = (*MemoryStore)(nil)
+	_ OrderStore       = (*MemoryStore)(nil)
+	_ BalanceStore     = (*MemoryStore)(nil)
+	_ StockStore       = (*MemoryStore)(nil)
+	_ APIKeyStore      = (*MemoryStore)(nil)
+	_ RoleStore        = (*MemoryStore)(nil)
+	_ IdempotencyStore = (*MemoryStore)(nil)
+)
+
+// memoryRolePermissions are the roles migration 000006 seeds.
+var memoryRolePermissions = map[string][]string{
+	"cashier": {"customers:read", "items:read", "transactions:read", "transactions:create"},
+	"manager": {"customers:read", "customers:write", "items:read", "items:write", "transactions:read", "transactions:create", "transactions:write", "transactions:void"},
+	"admin":   {"customers:read", "customers:write", "customers:delete", "items:read", "items:write", "items:delete", "transactions:read", "transactions:create", "transactions:write", "transactions:void", "api_keys:manage"},
+}
+
+func (s *MemoryStore) CreateCustomer(ctx context.Context, customer Customer) (Customer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.nextCustomerID++
+	createdCustomer := Customer{
+		ID:        s.nextCustomerID,
+		Name:      customer.Name,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+	s.customers[createdCustomer.ID] = createdCustomer
+	if !customer.Balance.IsZero() {
+		if _, err := s.postBalance(openingTopUp(createdCustomer.ID, customer.Balance), now); err != nil {
+			delete(s.customers, createdCustomer.ID)
+			return Customer{}, err
+		}
+	}
+	return s.customers[createdCustomer.ID], nil
+}
+
+func (s *MemoryStore) UpdateCustomer(ctx context.Context, customer Customer) (Customer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.customers[customer.ID]
+	if !ok || existing.DeletedAt != nil {
+		return Customer{}, NotFoundError("customer")
+	}
+	if customer.Version != 0 && customer.Version != existing.Version {
+		return Customer{}, StaleVersionError("customer")
+	}
+	existing.Name = customer.Name
+	existing.Version++
+	existing.UpdatedAt = time.Now()
+	s.customers[customer.ID] = existing
+	return existing, nil
+}
+
+func (s *MemoryStore) PatchCustomer(ctx context.Context, id int, patch CustomerPatch) (Customer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customer, ok := s.customers[id]
+	if !ok || customer.DeletedAt != nil {
+		return Customer{}, NotFoundError("customer")
+	}
+	if patch.Version != 0 && patch.Version != customer.Version {
+		return Customer{}, StaleVersionError("customer")
+	}
+	if patch.Name != nil {
+		customer.Name = *patch.Name
+	}
+	customer.Version++
+	customer.UpdatedAt = time.Now()
+	s.customers[id] = customer
+	return customer, nil
+}
+
+func (s *MemoryStore) DeleteCustomer(ctx context.Context, id int) (Customer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customer, ok := s.customers[id]
+	if !ok || customer.DeletedAt != nil {
+		return Customer{}, NotFoundError("customer")
+	}
+	now := time.Now()
+	customer.DeletedAt = &now
+	customer.Version++
+	customer.UpdatedAt = now
+	s.customers[id] = customer
+	return customer, nil
+}
+
+func (s *MemoryStore) RestoreCustomer(ctx context.Context, id int) (Customer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customer, ok := s.customers[id]
+	if !ok {
+		return Customer{}, NotFoundError("customer")
+	}
+	if customer.DeletedAt == nil {
+		return Customer{}, NotDeletedError("customer")
+	}
+	customer.DeletedAt = nil
+	customer.Version++
+	customer.UpdatedAt = time.Now()
+	s.customers[id] = customer
+	return customer, nil
+}
+
+func (s *MemoryStore) GetCustomer(ctx context.Context, id int, includeDeleted bool) (Customer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customer, ok := s.customers[id]
+	if !ok || !includeDeleted && customer.DeletedAt != nil {
+		return Customer{}, NotFoundError("customer")
+	}
+	return customer, nil
+}
+
+func (s *MemoryStore) GetCustomers(ctx context.Context, page PageRequest, includeDeleted bool) (Page[Customer], error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return Page[Customer]{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var customers []Customer
+	for _, customer := range s.customers {
+		if (includeDeleted || customer.DeletedAt == nil) && customer.ID > after.ID {
+			customers = append(customers, customer)
+		}
+	}
+	sort.Slice(customers, func(i, j int) bool { return customers[i].ID < customers[j].ID })
+	return newPage(truncate(customers, page), page, func(c Customer) cursor { return cursor{ID: c.ID} }), nil
+}
+
+func (s *MemoryStore) CreateItem(ctx context.Context, item Item) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.nextItemID++
+	createdItem := Item{
+		ID:             s.nextItemID,
+		Name:           item.Name,
+		Cost:           item.Cost,
+		Price:          item.Price,
+		Sort:           item.Sort,
+		AllowBackorder: item.AllowBackorder,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Version:        1,
+	}
+	s.items[createdItem.ID] = createdItem
+	return createdItem, nil
+}
+
+func (s *MemoryStore) UpdateItem(ctx context.Context, item Item) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.items[item.ID]
+	if !ok || existing.DeletedAt != nil {
+		return Item{}, NotFoundError("item")
+	}
+	if item.Version != 0 && item.Version != existing.Version {
+		return Item{}, StaleVersionError("item")
+	}
+	existing.Name = item.Name
+	existing.Cost = item.Cost
+	existing.Price = item.Price
+	existing.Sort = item.Sort
+	existing.AllowBackorder = item.AllowBackorder
+	existing.Version++
+	existing.UpdatedAt = time.Now()
+	s.items[item.ID] = existing
+	return existing, nil
+}
+
+func (s *MemoryStore) PatchItem(ctx context.Context, id int, patch ItemPatch) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok || item.DeletedAt != nil {
+		return Item{}, NotFoundError("item")
+	}
+	if patch.Version != 0 && patch.Version != item.Version {
+		return Item{}, StaleVersionError("item")
+	}
+	if patch.Name != nil {
+		item.Name = *patch.Name
+	}
+	if patch.Cost != nil {
+		item.Cost = *patch.Cost
+	}
+	if patch.Price != nil {
+		item.Price = *patch.Price
+	}
+	if patch.Sort != nil {
+		item.Sort = *patch.Sort
+	}
+	if patch.AllowBackorder != nil {
+		item.AllowBackorder = *patch.AllowBackorder
+	}
+	item.Version++
+	item.UpdatedAt = time.Now()
+	s.items[id] = item
+	return item, nil
+}
+
+func (s *MemoryStore) DeleteItem(ctx context.Context, id int) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok || item.DeletedAt != nil {
+		return Item{}, NotFoundError("item")
+	}
+	now := time.Now()
+	item.DeletedAt = &now
+	item.Version++
+	item.UpdatedAt = now
+	s.items[id] = item
+	return item, nil
+}
+
+func (s *MemoryStore) RestoreItem(ctx context.Context, id int) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return Item{}, NotFoundError("item")
+	}
+	if item.DeletedAt == nil {
+		return Item{}, NotDeletedError("item")
+	}
+	item.DeletedAt = nil
+	item.Version++
+	item.UpdatedAt = time.Now()
+	s.items[id] = item
+	return item, nil
+}
+
+func (s *MemoryStore) GetItem(ctx context.Context, id int, includeDeleted bool) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok || !includeDeleted && item.DeletedAt != nil {
+		return Item{}, NotFoundError("item")
+	}
+	return item, nil
+}
+
+func (s *MemoryStore) GetItems(ctx context.Context, page PageRequest, includeDeleted bool) (Page[Item], error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return Page[Item]{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []Item
+	for _, item := range s.items {
+		if (includeDeleted || item.DeletedAt == nil) && (page.Cursor == "" || item.Sort > after.Sort || item.Sort == after.Sort && item.ID > after.ID) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Sort != items[j].Sort {
+			return items[i].Sort < items[j].Sort
+		}
+		return items[i].ID < items[j].ID
+	})
+	return newPage(truncate(items, page), page, func(i Item) cursor { return cursor{ID: i.ID, Sort: i.Sort} }), nil
+}
+
+func (s *MemoryStore) GetTransactions(ctx context.Context, page PageRequest, includeDeleted bool) (Page[Transaction], error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return Page[Transaction]{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var transactions []Transaction
+	for _, id := range s.transactionIDs() {
+		transaction := s.transactions[id]
+		if (includeDeleted || transaction.DeletedAt == nil) && id > after.ID {
+			transactions = append(transactions, transaction.Transaction)
+		}
+	}
+	return newPage(truncate(transactions, page), page, func(t Transaction) cursor { return cursor{ID: t.ID} }), nil
+}
+
+func (s *MemoryStore) GetCustomerTransactions(ctx context.Context, customerID int, page PageRequest, includeDeleted bool) (Page[Transaction], error) {
+	s.mu.Lock()
+	customer, ok := s.customers[customerID]
+	s.mu.Unlock()
+	if !ok || !includeDeleted && customer.DeletedAt != nil {
+		return Page[Transaction]{}, NotFoundError("customer")
+	}
+	return s.getOwnedTransactions(page, includeDeleted, func(t Transaction) bool { return t.CustomerID == customerID })
+}
+
+func (s *MemoryStore) GetItemTransactions(ctx context.Context, itemID int, page PageRequest, includeDeleted bool) (Page[Transaction], error) {
+	s.mu.Lock()
+	item, ok := s.items[itemID]
+	s.mu.Unlock()
+	if !ok || !includeDeleted && item.DeletedAt != nil {
+		return Page[Transaction]{}, NotFoundError("item")
+	}
+	return s.getOwnedTransactions(page, includeDeleted, func(t Transaction) bool { return t.ItemID == itemID })
+}
+
+func (s *MemoryStore) getOwnedTransactions(page PageRequest, includeDeleted bool, owned func(Transaction) bool) (Page[Transaction], error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return Page[Transaction]{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var transactions []Transaction
+	for _, id := range s.transactionIDs() {
+		transaction := s.transactions[id]
+		if (includeDeleted || transaction.DeletedAt == nil) && id > after.ID && owned(transaction.Transaction) {
+			transactions = append(transactions, transaction.Transaction)
+		}
+	}
+	return newPage(truncate(transactions, page), page, func(t Transaction) cursor { return cursor{ID: t.ID} }), nil
+}
+
+func (s *MemoryStore) CreateTransaction(ctx context.Context, transaction Transaction) (Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, sales, err := s.createOrder(Order{CustomerID: transaction.CustomerID, Lines: []OrderLine{{ItemID: transaction.ItemID, Qty: transaction.Qty}}}, time.Now())
+	if err != nil {
+		return Transaction{}, singleLine(err)
+	}
+	return sales[0], nil
+}
+
+func (s *MemoryStore) CreateOrder(ctx context.Context, order Order) (Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	createdOrder, _, err := s.createOrder(order, time.Now())
+	return createdOrder, err
+}
+
+// createOrder mirrors PostgresStore.createOrder. Everything that can fail is
+// checked before the first line is booked. The caller holds s.mu.
+func (s *MemoryStore) createOrder(order Order, now time.Time) (Order, []Transaction, error) {
+	if err := checkOrder(order); err != nil {
+		return Order{}, nil, err
+	}
+	customer, ok := s.customers[order.CustomerID]
+	if !ok || customer.DeletedAt != nil {
+		return Order{}, nil, NotFoundError("customer")
+	}
+
+	var total Money
+	prices := make([]Money, len(order.Lines))
+	sold := make(map[int]int)
+	for i, line := range order.Lines {
+		item, ok := s.items[line.ItemID]
+		if !ok || item.DeletedAt != nil {
+			return Order{}, nil, &LineError{Line: i, Err: NotFoundError("item")}
+		}
+		prices[i] = item.Price
+		total = total.Add(item.Price.MulInt(line.Qty))
+		if customer.Balance.LessThan(total) {
+			return Order{}, nil, ErrInsufficientBalance
+		}
+		sold[item.ID] += line.Qty
+		if item.Stock-sold[item.ID] < 0 && !item.AllowBackorder {
+			return Order{}, nil, &LineError{Line: i, Err: ErrInsufficientStock}
+		}
+	}
+
+	s.nextOrderID++
+	createdOrder := Order{ID: s.nextOrderID, CustomerID: order.CustomerID, CreatedAt: now}
+	s.orders[createdOrder.ID] = createdOrder
+
+	orderID := createdOrder.ID
+	sales := make([]Transaction, 0, len(order.Lines))
+	for i, line := range order.Lines {
+		s.nextTransactionID++
+		sale := Transaction{
+			ID:         s.nextTransactionID,
+			CustomerID: order.CustomerID,
+			ItemID:     line.ItemID,
+			Qty:        line.Qty,
+			Amount:     prices[i].MulInt(line.Qty),
+			Type:       TransactionSale,
+			OrderID:    &orderID,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			Version:    1,
+		}
+		if !sale.Amount.IsZero() {
+			if _, err := s.postBalance(transactionEntry(sale), now); err != nil {
+				return Order{}, nil, err
+			}
+		}
+		if _, err := s.moveStock(saleMovement(sale, 0), now); err != nil {
+			return Order{}, nil, &LineError{Line: i, Err: err}
+		}
+		s.transactions[sale.ID] = memoryTransaction{Transaction: sale, price: prices[i]}
+
+		sales = append(sales, sale)
+		createdOrder.Lines = append(createdOrder.Lines, orderLine(sale, prices[i]))
+		createdOrder.Total = createdOrder.Total.Add(sale.Amount)
+	}
+	return createdOrder, sales, nil
+}
+
+func (s *MemoryStore) GetOrder(ctx context.Context, id int) (Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, ok := s.orders[id]
+	if !ok {
+		return Order{}, NotFoundError("order")
+	}
+	return s.withLines(order), nil
+}
+
+func (s *MemoryStore) GetCustomerOrders(ctx context.Context, customerID int, page PageRequest) (Page[Order], error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return Page[Order]{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.customers[customerID]; !ok {
+		return Page[Order]{}, NotFoundError("customer")
+	}
+	var orders []Order
+	for _, order := range s.orders {
+		if order.CustomerID == customerID && order.ID > after.ID {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+	orders = truncate(orders, page)
+	for i, order := range orders {
+		orders[i] = s.withLines(order)
+	}
+	return newPage(orders, page, func(o Order) cursor { return cursor{ID: o.ID} }), nil
+}
+
+// withLines returns order with its live lines and their total net of the
+// reversals of the lines. The caller holds s.mu.
+func (s *MemoryStore) withLines(order Order) Order {
+	order.Lines = nil
+	order.Total = Money{}
+	for _, id := range s.transactionIDs() {
+		transaction := s.transactions[id]
+		if transaction.OrderID == nil || *transaction.OrderID != order.ID || transaction.DeletedAt != nil {
+			continue
+		}
+		order.Lines = append(order.Lines, orderLine(transaction.Transaction, transaction.price))
+		order.Total = order.Total.Add(transaction.Amount)
+		for _, reversal := range s.transactions {
+			if reversal.OriginalTransactionID != nil && *reversal.OriginalTransactionID == id {
+				order.Total = order.Total.Add(reversal.Amount)
+			}
+		}
+	}
+	return order
+}
+
+func (s *MemoryStore) UpdateTransaction(ctx context.Context, transaction Transaction) (Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing := s.transactions[transaction.ID]
+	if err := s.checkUnreversedSale(transaction.ID); err != nil {
+		return Transaction{}, err
+	}
+	if transaction.Version != 0 && transaction.Version != existing.Version {
+		return Transaction{}, StaleVersionError("transaction")
+	}
+	return s.changeSale(existing, transaction.Qty, time.Now())
+}
+
+func (s *MemoryStore) PatchTransaction(ctx context.Context, id int, patch TransactionPatch) (Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transaction := s.transactions[id]
+	if err := s.checkUnreversedSale(id); err != nil {
+		return Transaction{}, err
+	}
+	if patch.Version != 0 && patch.Version != transaction.Version {
+		return Transaction{}, StaleVersionError("transaction")
+	}
+	qty := transaction.Qty
+	if patch.Qty != nil {
+		qty = *patch.Qty
+	}
+	return s.changeSale(transaction, qty, time.Now())
+}
+
+// changeSale mirrors changeTransaction: it sets the quantity of sale, posts
+// the change of its amount and books the change of its quantity. The balance
+// is checked before the stock moves, so nothing changes when either fails.
+// The caller holds s.mu.
+func (s *MemoryStore) changeSale(sale memoryTransaction, qty int, now time.Time) (Transaction, error) {
+	oldQty, oldAmount := sale.Qty, sale.Amount
+	sale.Qty = qty
+	sale.Amount = sale.price.MulInt(qty)
+
+	entry := saleChangeEntry(sale.Transaction, oldAmount)
+	if !entry.Amount.IsZero() {
+		if err := s.checkBalance(entry); err != nil {
+			return Transaction{}, err
+		}
+	}
+	if sale.Qty != oldQty {
+		if _, err := s.moveStock(saleMovement(sale.Transaction, oldQty), now); err != nil {
+			return Transaction{}, err
+		}
+	}
+	if !entry.Amount.IsZero() {
+		if _, err := s.postBalance(entry, now); err != nil {
+			return Transaction{}, err
+		}
+	}
+	sale.Version++
+	sale.UpdatedAt = now
+	s.transactions[sale.ID] = sale
+	return sale.Transaction, nil
+}
+
+func (s *MemoryStore) VoidTransaction(ctx context.Context, id int, reason string) (Transaction, error) {
+	return s.reverseTransaction(id, func(sale lockedSale) (Transaction, error) {
+		return voidOf(sale, reason)
+	})
+}
+
+func (s *MemoryStore) RefundTransaction(ctx context.Context, id int, qty int, reason string) (Transaction, error) {
+	return s.reverseTransaction(id, func(sale lockedSale) (Transaction, error) {
+		return refundOf(sale, qty, reason)
+	})
+}
+
+func (s *MemoryStore) reverseTransaction(id int, reverse func(lockedSale) (Transaction, error)) (Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	sale, err := s.sale(id, now)
+	if err != nil {
+		return Transaction{}, err
+	}
+	reversal, err := reverse(sale)
+	if err != nil {
+		return Transaction{}, err
+	}
+	reversal.ID = s.nextTransactionID + 1
+	reversal.CreatedAt = now
+	reversal.UpdatedAt = now
+	reversal.Version = 1
+
+	// Posting to the balance is the only step that can fail, so it goes first.
+	if !reversal.Amount.IsZero() {
+		if _, err := s.postBalance(transactionEntry(reversal), now); err != nil {
+			return Transaction{}, err
+		}
+	}
+	if _, err := s.moveStock(saleMovement(reversal, 0), now); err != nil {
+		return Transaction{}, err
+	}
+	s.nextTransactionID++
+	s.transactions[reversal.ID] = memoryTransaction{Transaction: reversal, price: sale.price}
+	return reversal, nil
+}
+
+// sale mirrors lockSale. The caller holds s.mu.
+func (s *MemoryStore) sale(id int, now time.Time) (lockedSale, error) {
+	transaction, ok := s.transactions[id]
+	if !ok || transaction.DeletedAt != nil {
+		return lockedSale{}, NotFoundError("transaction")
+	}
+	if transaction.Type != TransactionSale {
+		return lockedSale{}, ErrNotASale
+	}
+	sale := lockedSale{Transaction: transaction.Transaction, price: transaction.price, priced: true}
+	year, month, day := transaction.CreatedAt.Date()
+	todayYear, todayMonth, todayDay := now.Date()
+	sale.today = year == todayYear && month == todayMonth && day == todayDay
+	charged := false
+	for _, entry := range s.entries {
+		if entry.TransactionID != nil && *entry.TransactionID == id {
+			sale.paid = sale.paid.Sub(entry.Amount)
+			charged = true
+		}
+	}
+	if !charged {
+		sale.paid = sale.Amount
+	}
+	for _, reversal := range s.transactions {
+		if reversal.OriginalTransactionID != nil && *reversal.OriginalTransactionID == id {
+			sale.reversedQty -= reversal.Qty
+			sale.reversedAmount = sale.reversedAmount.Sub(reversal.Amount)
+		}
+	}
+	return sale, nil
+}
+
+// checkUnreversedSale fails unless id is a sale that may still be changed,
+// like changeTransaction requires. The caller holds s.mu.
+func (s *MemoryStore) checkUnreversedSale(id int) error {
+	sale, err := s.sale(id, time.Now())
+	if err != nil {
+		return err
+	}
+	if sale.reversedQty != 0 {
+		return ErrTransactionReversed
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteTransaction(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transaction, ok := s.transactions[id]
+	if !ok || transaction.DeletedAt != nil {
+		return NotFoundError("transaction")
+	}
+	now := time.Now()
+	transaction.DeletedAt = &now
+	transaction.Version++
+	transaction.UpdatedAt = now
+	s.transactions[id] = transaction
+	return nil
+}
+
+func (s *MemoryStore) RestoreTransaction(ctx context.Context, id int) (Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transaction, ok := s.transactions[id]
+	if !ok {
+		return Transaction{}, NotFoundError("transaction")
+	}
+	if transaction.DeletedAt == nil {
+		return Transaction{}, NotDeletedError("transaction")
+	}
+	now := time.Now()
+	transaction.DeletedAt = nil
+	transaction.Version++
+	transaction.UpdatedAt = now
+	s.transactions[id] = transaction
+	return transaction.Transaction, nil
+}
+
+func (s *MemoryStore) GetTransaction(ctx context.Context, id int, includeDeleted bool) (Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transaction, ok := s.transactions[id]
+	if !ok || !includeDeleted && transaction.DeletedAt != nil {
+		return Transaction{}, NotFoundError("transaction")
+	}
+	return transaction.Transaction, nil
+}
+
+func (s *MemoryStore) GetTransactionDetailsWithCustomerAndItem(ctx context.Context, page PageRequest) (Page[TransactionView], error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return Page[TransactionView]{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var transactions []TransactionView
+	for _, id := range s.transactionIDs() {
+		if view, ok := s.transactionView(s.transactions[id]); ok && id > after.ID {
+			transactions = append(transactions, view)
+		}
+	}
+	return newPage(truncate(transactions, page), page, func(t TransactionView) cursor { return cursor{ID: t.ID} }), nil
+}
+
+func (s *MemoryStore) FilterTransactions(ctx context.Context, filter TransactionFilter, page PageRequest) (Page[TransactionView], error) {
+	if err := filter.Validate(); err != nil {
+		return Page[TransactionView]{}, err
+	}
+	after, err := filter.cursorView(page.Cursor)
+	if err != nil {
+		return Page[TransactionView]{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var transactions []TransactionView
+	for _, transactionID := range s.transactionIDs() {
+		view, ok := s.transactionView(s.transactions[transactionID])
+		if ok && filter.matches(view) && (after.ID == 0 || filter.less(after, view)) {
+			transactions = append(transactions, view)
+		}
+	}
+	sort.SliceStable(transactions, func(i, j int) bool { return filter.less(transactions[i], transactions[j]) })
+	return newPage(truncate(transactions, page), page, filter.cursorOf), nil
+}
+
+// transactionView joins a transaction with its customer and item the way the
+// TransactionViews SQL view does. It reports false for deleted transactions
+// and when either side of the join is missing.
+func (s *MemoryStore) transactionView(transaction memoryTransaction) (TransactionView, bool) {
+	if transaction.DeletedAt != nil {
+		return TransactionView{}, false
+	}
+	customer, ok := s.customers[transaction.CustomerID]
+	if !ok {
+		return TransactionView{}, false
+	}
+	item, ok := s.items[transaction.ItemID]
+	if !ok {
+		return TransactionView{}, false
+	}
+	return TransactionView{
+		ID:                    transaction.ID,
+		CustomerID:            transaction.CustomerID,
+		CustomerName:          customer.Name,
+		ItemID:                transaction.ItemID,
+		ItemName:              item.Name,
+		Qty:                   transaction.Qty,
+		Price:                 transaction.price,
+		Amount:                transaction.Amount,
+		Type:                  transaction.Type,
+		OriginalTransactionID: transaction.OriginalTransactionID,
+		CreatedAt:             transaction.CreatedAt,
+		UpdatedAt:             transaction.UpdatedAt,
+	}, true
+}
+
+// truncate keeps the rows a LIMIT from PageRequest.limitClause would return.
+func truncate[T any](rows []T, page PageRequest) []T {
+	if page.Limit > 0 && len(rows) > page.Limit+1 {
+		return rows[:page.Limit+1]
+	}
+	return rows
+}
+
+func (s *MemoryStore) transactionIDs() []int {
+	ids := make([]int, 0, len(s.transactions))
+	for id := range s.transactions {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (s *MemoryStore) AddBalanceEntry(ctx context.Context, entry BalanceEntry) (BalanceEntry, error) {
+	if err := checkBalanceEntry(entry); err != nil {
+		return BalanceEntry{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.TransactionID = nil
+	return s.postBalance(entry, time.Now())
+}
+
+// postBalance mirrors the PostgreSQL postBalance. The caller holds s.mu.
+func (s *MemoryStore) postBalance(entry BalanceEntry, now time.Time) (BalanceEntry, error) {
+	if err := s.checkBalance(entry); err != nil {
+		return BalanceEntry{}, err
+	}
+	customer := s.customers[entry.CustomerID]
+	balanceAfter := customer.Balance.Add(entry.Amount)
+	customer.Balance = balanceAfter
+	customer.Version++
+	customer.UpdatedAt = now
+	s.customers[customer.ID] = customer
+
+	entry.ID = len(s.entries) + 1
+	entry.BalanceAfter = balanceAfter
+	entry.CreatedAt = now
+	s.entries = append(s.entries, entry)
+	return entry, nil
+}
+
+// checkBalance fails like postBalance would for entry. The caller holds s.mu.
+func (s *MemoryStore) checkBalance(entry BalanceEntry) error {
+	customer, ok := s.customers[entry.CustomerID]
+	if !ok || customer.DeletedAt != nil {
+		return NotFoundError("customer")
+	}
+	if customer.Balance.Add(entry.Amount).IsNegative() {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetBalanceStatement(ctx context.Context, customerID int, from, to time.Time) (BalanceStatement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.customers[customerID]; !ok {
+		return BalanceStatement{}, NotFoundError("customer")
+	}
+	statement := BalanceStatement{CustomerID: customerID, From: from, To: to}
+	for _, entry := range s.entries {
+		switch {
+		case entry.CustomerID != customerID || !entry.CreatedAt.Before(to):
+		case entry.CreatedAt.Before(from):
+			statement.OpeningBalance = entry.BalanceAfter
+		default:
+			statement.Entries = append(statement.Entries, entry)
+		}
+	}
+	statement.ClosingBalance = statement.OpeningBalance
+	if n := len(statement.Entries); n > 0 {
+		statement.ClosingBalance = statement.Entries[n-1].BalanceAfter
+	}
+	return statement, nil
+}
+
+func (s *MemoryStore) AddStockMovement(ctx context.Context, movement StockMovement) (StockMovement, error) {
+	if err := checkStockMovement(movement); err != nil {
+		return StockMovement{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	movement.TransactionID = nil
+	return s.moveStock(movement, time.Now())
+}
+
+// moveStock mirrors the PostgreSQL moveStock. The caller holds s.mu and must
+// not change anything else when it fails.
+func (s *MemoryStore) moveStock(movement StockMovement, now time.Time) (StockMovement, error) {
+	item, ok := s.items[movement.ItemID]
+	if !ok || item.DeletedAt != nil && movement.TransactionID == nil {
+		return StockMovement{}, NotFoundError("item")
+	}
+	if movement.Qty < 0 && item.Stock+movement.Qty < 0 && !item.AllowBackorder {
+		return StockMovement{}, ErrInsufficientStock
+	}
+	item.Stock += movement.Qty
+	s.items[item.ID] = item
+
+	movement.ID = len(s.movements) + 1
+	movement.StockAfter = item.Stock
+	movement.CreatedAt = now
+	s.movements = append(s.movements, movement)
+	return movement, nil
+}
+
+func (s *MemoryStore) GetStockMovements(ctx context.Context, itemID int, page PageRequest) (Page[StockMovement], error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return Page[StockMovement]{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.items[itemID]; !ok {
+		return Page[StockMovement]{}, NotFoundError("item")
+	}
+	var movements []StockMovement
+	for _, movement := range s.movements {
+		if movement.ItemID == itemID && movement.ID > after.ID {
+			movements = append(movements, movement)
+		}
+	}
+	return newPage(truncate(movements, page), page, func(m StockMovement) cursor { return cursor{ID: m.ID} }), nil
+}
+
+func (s *MemoryStore) CreateAPIKey(ctx context.Context, key APIKey) (APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := memoryRolePermissions[key.Role]; !ok {
+		return APIKey{}, NotFoundError("role")
+	}
+	for _, existing := range s.apiKeys {
+		if existing.Prefix == key.Prefix {
+			return APIKey{}, newError(ErrConflict, "unique_violation", "api_key already exists")
+		}
+	}
+	s.nextAPIKeyID++
+	createdKey := APIKey{
+		ID:        s.nextAPIKeyID,
+		Name:      key.Name,
+		Role:      key.Role,
+		Prefix:    key.Prefix,
+		Hash:      key.Hash,
+		CreatedAt: time.Now(),
+	}
+	s.apiKeys[createdKey.ID] = createdKey
+	return createdKey, nil
+}
+
+func (s *MemoryStore) GetAPIKeys(ctx context.Context, includeRevoked bool) ([]APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []APIKey
+	for _, key := range s.apiKeys {
+		if includeRevoked || key.RevokedAt == nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (s *MemoryStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range s.apiKeys {
+		if key.Prefix == prefix {
+			return key, nil
+		}
+	}
+	return APIKey{}, NotFoundError("api_key")
+}
+
+func (s *MemoryStore) RevokeAPIKey(ctx context.Context, id int) (APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.apiKeys[id]
+	if !ok {
+		return APIKey{}, NotFoundError("api_key")
+	}
+	if key.RevokedAt != nil {
+		return APIKey{}, ErrAPIKeyRevoked
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	s.apiKeys[id] = key
+	return key, nil
+}
+
+func (s *MemoryStore) TouchAPIKey(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.apiKeys[id]
+	if !ok {
+		return NotFoundError("api_key")
+	}
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		key.LastUsedAt = &now
+		s.apiKeys[id] = key
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetRolePermissions(ctx context.Context, roles []string) ([]string, error) {
+	granted := make(map[string]bool)
+	for _, role := range roles {
+		for _, permission := range memoryRolePermissions[role] {
+			granted[permission] = true
+		}
+	}
+	permissions := make([]string, 0, len(granted))
+	for permission := range granted {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+func (s *MemoryStore) ReserveIdempotencyKey(ctx context.Context, record IdempotencyRecord, ttl time.Duration) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	id := [2]string{record.Principal, record.Key}
+	if existing, ok := s.idempotency[id]; ok && existing.ExpiresAt.After(now) {
+		return existing, false, nil
+	}
+	record.Status, record.Header, record.Body = 0, nil, nil
+	record.CreatedAt, record.ExpiresAt = now, now.Add(ttl)
+	s.idempotency[id] = record
+	return record, true, nil
+}
+
+func (s *MemoryStore) CompleteIdempotencyKey(ctx context.Context, record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := [2]string{record.Principal, record.Key}
+	existing, ok := s.idempotency[id]
+	if !ok {
+		return nil
+	}
+	existing.Status, existing.Header, existing.Body = record.Status, record.Header, record.Body
+	s.idempotency[id] = existing
+	return nil
+}
+
+func (s *MemoryStore) ReleaseIdempotencyKey(ctx context.Context, principal, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := [2]string{principal, key}
+	if existing, ok := s.idempotency[id]; ok && existing.Status == 0 {
+		delete(s.idempotency, id)
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var deleted int64
+	for id, record := range s.idempotency {
+		if !record.ExpiresAt.After(now) {
+			delete(s.idempotency, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,278 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"lesson/storage"
+	"lesson/storage/storagetest"
+)
+
+func money(t *testing.T, s string) storage.Money {
+	t.Helper()
+	m, err := storage.ParseMoney(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// seed creates a customer with balance and an item with price and stock.
+func seed(t *testing.T, store storagetest.Store, balance, price string, stock int) (storage.Customer, storage.Item) {
+	t.Helper()
+	ctx := context.Background()
+	customer, err := store.CreateCustomer(ctx, storage.Customer{Name: "John Doe", Balance: money(t, balance)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := store.CreateItem(ctx, storage.Item{Name: "Laptop", Price: money(t, price)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stock > 0 {
+		if _, err := store.AddStockMovement(ctx, storage.StockMovement{ItemID: item.ID, Kind: storage.StockRestock, Qty: stock}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return customer, item
+}
+
+// assertHoldings checks the balance of customerID and the stock of itemID.
+func assertHoldings(t *testing.T, store storagetest.Store, customerID int, balance string, itemID, stock int) {
+	t.Helper()
+	ctx := context.Background()
+	customer, err := store.GetCustomer(ctx, customerID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customer.Balance.String() != balance {
+		t.Errorf("balance = %s, want %s", customer.Balance, balance)
+	}
+	item, err := store.GetItem(ctx, itemID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Stock != stock {
+		t.Errorf("stock = %d, want %d", item.Stock, stock)
+	}
+}
+
+func TestCreateTransaction(t *testing.T) {
+	tests := []struct {
+		name        string
+		balance     string
+		stock       int
+		qty         int
+		wantErr     error
+		wantAmount  string
+		wantBalance string
+		wantStock   int
+	}{
+		{name: "debits balance and stock", balance: "100.00", stock: 5, qty: 2, wantAmount: "25.00", wantBalance: "75.00", wantStock: 3},
+		{name: "spends the whole balance", balance: "25.00", stock: 2, qty: 2, wantAmount: "25.00", wantBalance: "0.00", wantStock: 0},
+		{name: "insufficient balance", balance: "10.00", stock: 5, qty: 2, wantErr: storage.ErrInsufficientBalance, wantBalance: "10.00", wantStock: 5},
+		{name: "insufficient stock", balance: "100.00", stock: 1, qty: 2, wantErr: storage.ErrInsufficientStock, wantBalance: "100.00", wantStock: 1},
+		{name: "zero quantity", balance: "100.00", stock: 5, qty: 0, wantErr: storage.ErrInvalidQuantity, wantBalance: "100.00", wantStock: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+				customer, item := seed(t, store, tt.balance, "12.50", tt.stock)
+
+				sale, err := store.CreateTransaction(context.Background(), storage.Transaction{CustomerID: customer.ID, ItemID: item.ID, Qty: tt.qty})
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if err == nil && sale.Amount.String() != tt.wantAmount {
+					t.Errorf("amount = %s, want %s", sale.Amount, tt.wantAmount)
+				}
+				assertHoldings(t, store, customer.ID, tt.wantBalance, item.ID, tt.wantStock)
+			})
+		})
+	}
+}
+
+func TestRefundTransaction(t *testing.T) {
+	tests := []struct {
+		name        string
+		refunds     []int
+		wantErr     error
+		wantBalance string
+		wantStock   int
+	}{
+		{name: "some units", refunds: []int{1}, wantBalance: "80.00", wantStock: 3},
+		{name: "every unit in parts", refunds: []int{1, 2}, wantBalance: "100.00", wantStock: 5},
+		{name: "past the remaining quantity", refunds: []int{2, 2}, wantErr: storage.ErrRefundExceedsSale, wantBalance: "90.00", wantStock: 4},
+		{name: "more than was sold", refunds: []int{4}, wantErr: storage.ErrRefundExceedsSale, wantBalance: "70.00", wantStock: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+				ctx := context.Background()
+				customer, item := seed(t, store, "100.00", "10.00", 5)
+				sale, err := store.CreateTransaction(ctx, storage.Transaction{CustomerID: customer.ID, ItemID: item.ID, Qty: 3})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for i, qty := range tt.refunds {
+					refund, err := store.RefundTransaction(ctx, sale.ID, qty, "wrong size")
+					if i < len(tt.refunds)-1 || tt.wantErr == nil {
+						if err != nil {
+							t.Fatalf("refund %d: %v", i, err)
+						}
+						if refund.Type != storage.TransactionRefund || refund.Qty != -qty {
+							t.Errorf("refund %d: type %s, qty %d", i, refund.Type, refund.Qty)
+						}
+						continue
+					}
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("refund %d: err = %v, want %v", i, err, tt.wantErr)
+					}
+				}
+				assertHoldings(t, store, customer.ID, tt.wantBalance, item.ID, tt.wantStock)
+			})
+		})
+	}
+}
+
+func TestVoidTransaction(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+		ctx := context.Background()
+		customer, item := seed(t, store, "100.00", "10.00", 5)
+		sale, err := store.CreateTransaction(ctx, storage.Transaction{CustomerID: customer.ID, ItemID: item.ID, Qty: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		void, err := store.VoidTransaction(ctx, sale.ID, "rung up twice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if void.Type != storage.TransactionVoid || void.Amount.String() != "-20.00" {
+			t.Errorf("void: type %s, amount %s", void.Type, void.Amount)
+		}
+		assertHoldings(t, store, customer.ID, "100.00", item.ID, 5)
+
+		if _, err := store.VoidTransaction(ctx, sale.ID, ""); !errors.Is(err, storage.ErrTransactionReversed) || !errors.Is(err, storage.ErrConflict) {
+			t.Errorf("second void: err = %v, want %v", err, storage.ErrTransactionReversed)
+		}
+		if _, err := store.RefundTransaction(ctx, sale.ID, 1, "wrong size"); !errors.Is(err, storage.ErrRefundExceedsSale) {
+			t.Errorf("refund of a void sale: err = %v, want %v", err, storage.ErrRefundExceedsSale)
+		}
+		if _, err := store.VoidTransaction(ctx, void.ID, ""); !errors.Is(err, storage.ErrNotASale) {
+			t.Errorf("void of a void: err = %v, want %v", err, storage.ErrNotASale)
+		}
+		assertHoldings(t, store, customer.ID, "100.00", item.ID, 5)
+	})
+}
+
+func TestUpdateStaleVersion(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+		ctx := context.Background()
+		customer, err := store.CreateCustomer(ctx, storage.Customer{Name: "John Doe"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		updated, err := store.UpdateCustomer(ctx, storage.Customer{ID: customer.ID, Name: "Jane Doe", Version: customer.Version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Version != customer.Version+1 {
+			t.Errorf("version = %d, want %d", updated.Version, customer.Version+1)
+		}
+		if _, err := store.UpdateCustomer(ctx, storage.Customer{ID: customer.ID, Name: "John Doe", Version: customer.Version}); !errors.Is(err, storage.ErrStaleVersion) {
+			t.Errorf("stale update: err = %v, want %v", err, storage.ErrStaleVersion)
+		}
+		if _, err := store.UpdateCustomer(ctx, storage.Customer{ID: customer.ID, Name: "John Doe"}); err != nil {
+			t.Errorf("unconditional update: %v", err)
+		}
+	})
+}
+
+func TestFilterTransactionsPages(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+		ctx := context.Background()
+		customer, item := seed(t, store, "1000.00", "10.00", 20)
+		for _, qty := range []int{2, 1, 3, 1, 2} {
+			if _, err := store.CreateTransaction(ctx, storage.Transaction{CustomerID: customer.ID, ItemID: item.ID, Qty: qty}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, sort := range []string{"", "id", "qty", "amount", "price", "created_at", "customer_name"} {
+			for _, order := range []string{"asc", "desc"} {
+				filter := storage.TransactionFilter{CustomerIDs: []int{customer.ID}, Sort: sort, Order: order}
+				all, err := store.FilterTransactions(ctx, filter, storage.PageRequest{Limit: storage.MaxPageLimit})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(all.Data) != 5 || all.NextCursor != "" {
+					t.Fatalf("sort %q %s: %d rows, next cursor %q", sort, order, len(all.Data), all.NextCursor)
+				}
+
+				var paged []storage.TransactionView
+				page := storage.PageRequest{Limit: 2}
+				for {
+					result, err := store.FilterTransactions(ctx, filter, page)
+					if err != nil {
+						t.Fatal(err)
+					}
+					paged = append(paged, result.Data...)
+					if result.NextCursor == "" {
+						break
+					}
+					page.Cursor = result.NextCursor
+				}
+				if len(paged) != len(all.Data) {
+					t.Fatalf("sort %q %s: pages hold %d rows, want %d", sort, order, len(paged), len(all.Data))
+				}
+				for i := range paged {
+					if paged[i].ID != all.Data[i].ID {
+						t.Errorf("sort %q %s: row %d is %d, want %d", sort, order, i, paged[i].ID, all.Data[i].ID)
+					}
+				}
+			}
+		}
+
+		if _, err := store.FilterTransactions(ctx, storage.TransactionFilter{}, storage.PageRequest{Limit: 2, Cursor: "not a cursor"}); !errors.Is(err, storage.ErrInvalidArgument) {
+			t.Errorf("invalid cursor: err = %v, want %v", err, storage.ErrInvalidArgument)
+		}
+	})
+}
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, store storagetest.Store) {
+		ctx := context.Background()
+		record := storage.IdempotencyRecord{Principal: "api_key:api_key:1", Key: "f1b2", RequestHash: []byte("hash")}
+
+		if _, reserved, err := store.ReserveIdempotencyKey(ctx, record, time.Hour); err != nil || !reserved {
+			t.Fatalf("first reserve: reserved %v, err %v", reserved, err)
+		}
+		if _, reserved, err := store.ReserveIdempotencyKey(ctx, record, time.Hour); err != nil || reserved {
+			t.Fatalf("reserve while in flight: reserved %v, err %v", reserved, err)
+		}
+
+		record.Status = 201
+		record.Body = []byte(`{"id":1}`)
+		if err := store.CompleteIdempotencyKey(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+		stored, reserved, err := store.ReserveIdempotencyKey(ctx, record, time.Hour)
+		if err != nil || reserved {
+			t.Fatalf("reserve after completion: reserved %v, err %v", reserved, err)
+		}
+		if stored.Status != 201 || string(stored.Body) != `{"id":1}` {
+			t.Errorf("stored response = %d %s", stored.Status, stored.Body)
+		}
+
+		other := record
+		other.Principal = "none:"
+		if _, reserved, err := store.ReserveIdempotencyKey(ctx, other, time.Hour); err != nil || !reserved {
+			t.Errorf("same key of another principal: reserved %v, err %v", reserved, err)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// MoneyScale is the number of fractional digits kept for monetary amounts. It
+// matches the NUMERIC(14, 2) money columns.
+const MoneyScale = 2
+
+// Money is an exact decimal amount. It scans from and writes to NUMERIC
+// columns without going through float64 and is encoded in JSON as a string
+// with MoneyScale fractional digits, e.g. "2599.90".
+type Money struct {
+	d decimal.Decimal
+}
+
+// ParseMoney parses a decimal string such as "12.5". It rejects values with
+// more than MoneyScale fractional digits instead of rounding them.
+func ParseMoney(s string) (Money, error) {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if !d.Equal(d.Round(MoneyScale)) {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places", s, MoneyScale)
+	}
+	return Money{d: d}, nil
+}
+
+// MoneyFromInt returns a whole amount of units.
+func MoneyFromInt(units int64) Money {
+	return Money{d: decimal.NewFromInt(units)}
+}
+
+func (m Money) Add(other Money) Money {
+	return Money{d: m.d.Add(other.d)}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{d: m.d.Sub(other.d)}
+}
+
+// MulInt multiplies the amount by a quantity, e.g. a unit price by qty.
+func (m Money) MulInt(n int) Money {
+	return Money{d: m.d.Mul(decimal.NewFromInt(int64(n)))}
+}
+
+// MulRatio multiplies the amount by num/den and rounds it to MoneyScale, e.g.
+// to scale the amount of a sale to another quantity.
+func (m Money) MulRatio(num, den int) Money {
+	return Money{d: m.d.Mul(decimal.NewFromInt(int64(num))).Div(decimal.NewFromInt(int64(den))).Round(MoneyScale)}
+}
+
+// Cmp returns -1, 0 or 1 depending on whether m is less than, equal to or
+// greater than other.
+func (m Money) Cmp(other Money) int {
+	return m.d.Cmp(other.d)
+}
+
+func (m Money) LessThan(other Money) bool {
+	return m.d.LessThan(other.d)
+}
+
+func (m Money) IsNegative() bool {
+	return m.d.IsNegative()
+}
+
+func (m Money) IsZero() bool {
+	return m.d.IsZero()
+}
+
+// InexactFloat64 returns the nearest float64. It is meant for range checks
+// and must not be used for arithmetic.
+func (m Money) InexactFloat64() float64 {
+	return m.d.InexactFloat64()
+}
+
+func (m Money) String() string {
+	return m.d.StringFixed(MoneyScale)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON accepts both JSON strings and plain JSON numbers so that
+// existing clients sending numbers keep working.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var s string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	} else {
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("invalid amount %s", data)
+		}
+		s = n.String()
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner. NULL scans as zero.
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = MoneyFromInt(v)
+		return nil
+	case float64:
+		*m = Money{d: decimal.NewFromFloat(v).Round(MoneyScale)}
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into Money", value)
+}
+
+func (m *Money) scanString(s string) error {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return err
+	}
+	*m = Money{d: d.Round(MoneyScale)}
+	return nil
+}
+
+// Value implements driver.Valuer, writing the exact decimal as text.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Order is the receipt of a purchase of one or more items. Each line is a
+// sale in the transactions, so lines are voided, refunded and reported like
+// any other sale. Total is the sum of the amounts of the live lines less what
+// their voids and refunds returned.
+type Order struct {
+	ID         int
+	CustomerID int
+	Lines      []OrderLine
+	Total      Money
+	CreatedAt  time.Time
+}
+
+// OrderLine is a line of an order: Qty units of an item sold at the unit
+// Price. TransactionID is the sale that records the line and Amount its line
+// total.
+type OrderLine struct {
+	TransactionID int
+	ItemID        int
+	Qty           int
+	Price         Money
+	Amount        Money
+}
+
+var ErrEmptyOrder = newError(ErrValidation, "empty_order", "an order needs at least one line")
+
+// LineError is the error of one line of an order. Line counts from zero.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// checkOrder validates the lines of an order before anything is booked.
+func checkOrder(order Order) error {
+	if len(order.Lines) == 0 {
+		return ErrEmptyOrder
+	}
+	for i, line := range order.Lines {
+		if line.Qty <= 0 {
+			return &LineError{Line: i, Err: ErrInvalidQuantity}
+		}
+	}
+	return nil
+}
+
+// singleLine returns the error of the only line of the order CreateTransaction
+// places, which has no lines to report.
+func singleLine(err error) error {
+	var lineErr *LineError
+	if errors.As(err, &lineErr) {
+		return lineErr.Err
+	}
+	return err
+}
+
+// orderLine is the line a sale records.
+func orderLine(sale Transaction, price Money) OrderLine {
+	return OrderLine{TransactionID: sale.ID, ItemID: sale.ItemID, Qty: sale.Qty, Price: price, Amount: sale.Amount}
+}
+
+// CreateOrder records the purchase of every line of order in one database
+// transaction: each line is inserted as a sale at the item's current price,
+// posted to the customer's balance ledger and booked in the stock ledger.
+// Prices and amounts in order are ignored.
+func (s *PostgresStore) CreateOrder(ctx context.Context, order Order) (Order, error) {
+	createdOrder, _, err := s.createOrder(ctx, order)
+	return createdOrder, err
+}
+
+func (s *PostgresStore) createOrder(ctx context.Context, order Order) (Order, []Transaction, error) {
+	if err := checkOrder(order); err != nil {
+		return Order{}, nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Order{}, nil, translateError(err, "order")
+	}
+	defer tx.Rollback()
+
+	// Locking the customer first keeps concurrent purchases from interleaving
+	// their balance entries.
+	var exists bool
+	err = s.inTx(tx).QueryRowContext(ctx, "SELECT true FROM tbl_customer WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", order.CustomerID).
+		Scan(&exists)
+	if err != nil {
+		return Order{}, nil, translateError(err, "customer")
+	}
+
+	// Locking the items in id order before the lines book their stock keeps
+	// concurrent orders of the same items in another order from deadlocking.
+	itemIDs := make([]int, len(order.Lines))
+	for i, line := range order.Lines {
+		itemIDs[i] = line.ItemID
+	}
+	if _, err := s.inTx(tx).ExecContext(ctx, "SELECT id FROM tbl_items WHERE id = ANY($1) ORDER BY id FOR UPDATE", pq.Array(itemIDs)); err != nil {
+		return Order{}, nil, translateError(err, "item")
+	}
+
+	createdOrder := Order{CustomerID: order.CustomerID}
+	err = s.inTx(tx).QueryRowContext(ctx, "INSERT INTO tbl_orders (customer_id) VALUES ($1) RETURNING id, created_at", order.CustomerID).
+		Scan(&createdOrder.ID, &createdOrder.CreatedAt)
+	if err != nil {
+		return Order{}, nil, translateError(err, "order")
+	}
+
+	sales := make([]Transaction, 0, len(order.Lines))
+	for i, line := range order.Lines {
+		var price Money
+		err = s.inTx(tx).QueryRowContext(ctx, "SELECT COALESCE(price, 0) FROM tbl_items WHERE id = $1 AND deleted_at IS NULL", line.ItemID).
+			Scan(&price)
+		if err != nil {
+			return Order{}, nil, &LineError{Line: i, Err: translateError(err, "item")}
+		}
+
+		sale, err := scanTransaction(s.inTx(tx).QueryRowContext(ctx, "INSERT INTO tbl_transaction (customer_id, item_id, qty, price, amount, order_id, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING "+transactionColumns,
+			order.CustomerID, line.ItemID, line.Qty, price, price.MulInt(line.Qty), createdOrder.ID, createdOrder.CreatedAt))
+		if err != nil {
+			return Order{}, nil, translateError(err, "transaction")
+		}
+
+		// Items priced at zero have nothing to post.
+		if !sale.Amount.IsZero() {
+			if _, err := postBalance(ctx, s.inTx(tx), transactionEntry(sale)); err != nil {
+				return Order{}, nil, err
+			}
+		}
+		if _, err := moveStock(ctx, s.inTx(tx), saleMovement(sale, 0)); err != nil {
+			return Order{}, nil, &LineError{Line: i, Err: err}
+		}
+
+		sales = append(sales, sale)
+		createdOrder.Lines = append(createdOrder.Lines, orderLine(sale, price))
+		createdOrder.Total = createdOrder.Total.Add(sale.Amount)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Order{}, nil, translateError(err, "order")
+	}
+	return createdOrder, sales, nil
+}
+
+func (s *PostgresStore) GetOrder(ctx context.Context, id int) (Order, error) {
+	var order Order
+	err := s.conn().QueryRowContext(ctx, "SELECT id, customer_id, created_at FROM tbl_orders WHERE id = $1", id).
+		Scan(&order.ID, &order.CustomerID, &order.CreatedAt)
+	if err != nil {
+		return Order{}, translateError(err, "order")
+	}
+	orders := []Order{order}
+	if err := s.addOrderLines(ctx, orders, "order_id = $1", id); err != nil {
+		return Order{}, err
+	}
+	return orders[0], nil
+}
+
+func (s *PostgresStore) GetCustomerOrders(ctx context.Context, customerID int, page PageRequest) (Page[Order], error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return Page[Order]{}, translateError(err, "order")
+	}
+	var exists bool
+	if err := s.conn().QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM tbl_customer WHERE id = $1)", customerID).Scan(&exists); err != nil {
+		return Page[Order]{}, translateError(err, "customer")
+	}
+	if !exists {
+		return Page[Order]{}, NotFoundError("customer")
+	}
+
+	rows, err := s.conn().QueryContext(ctx, "SELECT id, customer_id, created_at FROM tbl_orders WHERE customer_id = $1 AND id > $2 ORDER BY id LIMIT $3", customerID, after.ID, page.limitClause())
+	if err != nil {
+		return Page[Order]{}, translateError(err, "order")
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		var order Order
+		if err := rows.Scan(&order.ID, &order.CustomerID, &order.CreatedAt); err != nil {
+			return Page[Order]{}, translateError(err, "order")
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[Order]{}, translateError(err, "order")
+	}
+
+	if len(orders) > 0 {
+		if err := s.addOrderLines(ctx, orders, "order_id IN (SELECT id FROM tbl_orders WHERE customer_id = $1 AND id > $2 ORDER BY id LIMIT $3)", customerID, after.ID, page.limitClause()); err != nil {
+			return Page[Order]{}, err
+		}
+	}
+	return newPage(orders, page, func(o Order) cursor { return cursor{ID: o.ID} }), nil
+}
+
+// addOrderLines loads the live lines of orders, which where selects, into
+// them and adds up their totals net of the reversals of the lines.
+func (s *PostgresStore) addOrderLines(ctx context.Context, orders []Order, where string, args ...interface{}) error {
+	index := make(map[int]int, len(orders))
+	for i, order := range orders {
+		index[order.ID] = i
+	}
+
+	rows, err := s.conn().QueryContext(ctx, "SELECT order_id, id, item_id, qty, COALESCE(price, 0), amount, (SELECT COALESCE(SUM(r.amount), 0) FROM tbl_transaction r WHERE r.original_transaction_id = tbl_transaction.id) FROM tbl_transaction WHERE "+where+" AND deleted_at IS NULL ORDER BY id", args...)
+	if err != nil {
+		return translateError(err, "order")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID int
+		var line OrderLine
+		var reversed Money
+		if err := rows.Scan(&orderID, &line.TransactionID, &line.ItemID, &line.Qty, &line.Price, &line.Amount, &reversed); err != nil {
+			return translateError(err, "order")
+		}
+		i := index[orderID]
+		orders[i].Lines = append(orders[i].Lines, line)
+		orders[i].Total = orders[i].Total.Add(line.Amount).Add(reversed)
+	}
+	if err := rows.Err(); err != nil {
+		return translateError(err, "order")
+	}
+	return nil
+}
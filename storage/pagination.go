@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+var ErrInvalidCursor = InvalidArgumentError("invalid_cursor", "invalid cursor")
+
+// PageRequest selects one page of a keyset-paginated list. A zero Limit
+// returns every remaining row; Cursor is the NextCursor of the previous page
+// and is empty for the first one.
+type PageRequest struct {
+	Limit  int
+	Cursor string
+}
+
+// Page is one page of a list. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// cursor is the position after which the next page starts. Lists ordered by
+// id only use ID; items are ordered by (sort, id) and filtered transactions
+// by the sort column, whose value Key holds, and id.
+type cursor struct {
+	ID   int    `json:"id"`
+	Sort int    `json:"sort,omitempty"`
+	Key  string `json:"key,omitempty"`
+}
+
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// limitClause returns the LIMIT to use in SQL. One extra row is fetched to
+// find out whether another page follows.
+func (p PageRequest) limitClause() interface{} {
+	if p.Limit <= 0 {
+		return nil
+	}
+	return p.Limit + 1
+}
+
+// newPage trims rows fetched with limitClause down to the page size and
+// computes the cursor of the next page from the last row kept.
+func newPage[T any](rows []T, p PageRequest, cursorOf func(T) cursor) Page[T] {
+	page := Page[T]{Data: rows}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	if p.Limit > 0 && len(rows) > p.Limit {
+		page.Data = rows[:p.Limit]
+		page.NextCursor = cursorOf(page.Data[p.Limit-1]).encode()
+	}
+	return page
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+)
+
+var (
+	ErrNotASale            = newError(ErrValidation, "not_a_sale", "only sales can be changed, voided or refunded")
+	ErrTransactionReversed = newError(ErrConflict, "transaction_reversed", "the transaction was voided or refunded")
+	ErrVoidWindowClosed    = newError(ErrValidation, "void_window_closed", "only transactions of the current day can be voided, refund them instead")
+	ErrRefundExceedsSale   = newError(ErrValidation, "refund_exceeds_sale", "the refund exceeds the quantity not yet refunded")
+)
+
+// lockedSale is a live sale together with what its voids and refunds have
+// reversed so far. Reversals count even when they were deleted, as deleting
+// them does not undo their balance and stock entries. priced reports whether
+// the sale kept its unit price. paid is what the balance ledger charged the
+// customer for the sale, which is what reversals return. Sales recorded
+// before the ledger existed have no entries and count their amount, which
+// was debited from the balance directly.
+type lockedSale struct {
+	Transaction
+	price          Money
+	priced         bool
+	today          bool
+	paid           Money
+	reversedQty    int
+	reversedAmount Money
+}
+
+// lockSale locks the live sale id for the rest of the database transaction
+// q belongs to. Voids and refunds cannot be reversed themselves and fail
+// with ErrNotASale.
+func lockSale(ctx context.Context, q queryer, id int) (lockedSale, error) {
+	transaction, err := scanTransaction(q.QueryRowContext(ctx, "SELECT "+transactionColumns+" FROM tbl_transaction WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id))
+	if err != nil {
+		return lockedSale{}, translateError(err, "transaction")
+	}
+	if transaction.Type != TransactionSale {
+		return lockedSale{}, ErrNotASale
+	}
+	sale := lockedSale{Transaction: transaction}
+	err = q.QueryRowContext(ctx, "SELECT COALESCE(price, 0), price IS NOT NULL, created_at >= CURRENT_DATE, (SELECT COALESCE(-SUM(amount), t.amount, 0) FROM tbl_balance_entries WHERE transaction_id = $1), (SELECT COALESCE(-SUM(qty), 0) FROM tbl_transaction WHERE original_transaction_id = $1), (SELECT COALESCE(-SUM(amount), 0) FROM tbl_transaction WHERE original_transaction_id = $1) FROM tbl_transaction t WHERE id = $1", id).
+		Scan(&sale.price, &sale.priced, &sale.today, &sale.paid, &sale.reversedQty, &sale.reversedAmount)
+	if err != nil {
+		return lockedSale{}, translateError(err, "transaction")
+	}
+	return sale, nil
+}
+
+// voidOf returns the void of sale: a full reversal, allowed on the day of the
+// sale as long as nothing was refunded yet.
+func voidOf(sale lockedSale, reason string) (Transaction, error) {
+	if sale.reversedQty != 0 {
+		return Transaction{}, ErrTransactionReversed
+	}
+	if !sale.today {
+		return Transaction{}, ErrVoidWindowClosed
+	}
+	return reversalOf(sale, TransactionVoid, sale.Qty, sale.paid, reason), nil
+}
+
+// refundOf returns the refund of qty units of sale. Units are refunded at
+// the unit price of the sale, see amountFor; the refund of the last units
+// returns what is left of what was paid, so that refunds never add up to
+// more than that.
+func refundOf(sale lockedSale, qty int, reason string) (Transaction, error) {
+	if qty <= 0 {
+		return Transaction{}, ErrInvalidQuantity
+	}
+	remainingQty := sale.Qty - sale.reversedQty
+	if qty > remainingQty {
+		return Transaction{}, ErrRefundExceedsSale
+	}
+	remainingAmount := sale.paid.Sub(sale.reversedAmount)
+	amount, err := sale.amountFor(qty)
+	if err != nil {
+		return Transaction{}, err
+	}
+	if qty == remainingQty || remainingAmount.LessThan(amount) {
+		amount = remainingAmount
+	}
+	return reversalOf(sale, TransactionRefund, qty, amount, reason), nil
+}
+
+func reversalOf(sale lockedSale, kind string, qty int, amount Money, reason string) Transaction {
+	id := sale.ID
+	return Transaction{
+		CustomerID:            sale.CustomerID,
+		ItemID:                sale.ItemID,
+		Qty:                   -qty,
+		Amount:                Money{}.Sub(amount),
+		Type:                  kind,
+		OriginalTransactionID: &id,
+		Reason:                reason,
+	}
+}
+
+// VoidTransaction reverses a sale of the current day in full. The customer is
+// refunded and the units are returned to stock in the same database
+// transaction that records the void.
+func (s *PostgresStore) VoidTransaction(ctx context.Context, id int, reason string) (Transaction, error) {
+	return s.reverseTransaction(ctx, id, func(sale lockedSale) (Transaction, error) {
+		return voidOf(sale, reason)
+	})
+}
+
+// RefundTransaction refunds qty units of a sale, at most as many as were not
+// refunded yet. Like VoidTransaction it credits the customer and returns the
+// units to stock.
+func (s *PostgresStore) RefundTransaction(ctx context.Context, id int, qty int, reason string) (Transaction, error) {
+	return s.reverseTransaction(ctx, id, func(sale lockedSale) (Transaction, error) {
+		return refundOf(sale, qty, reason)
+	})
+}
+
+// reverseTransaction locks the sale id, records the reversal that reverse
+// returns for it and posts the balance and stock entries of the reversal.
+func (s *PostgresStore) reverseTransaction(ctx context.Context, id int, reverse func(lockedSale) (Transaction, error)) (Transaction, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Transaction{}, translateError(err, "transaction")
+	}
+	defer tx.Rollback()
+
+	sale, err := lockSale(ctx, s.inTx(tx), id)
+	if err != nil {
+		return Transaction{}, err
+	}
+	reversal, err := reverse(sale)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	createdReversal, err := scanTransaction(s.inTx(tx).QueryRowContext(ctx, "INSERT INTO tbl_transaction (customer_id, item_id, qty, price, amount, type, original_transaction_id, reason) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING "+transactionColumns,
+		reversal.CustomerID, reversal.ItemID, reversal.Qty, sale.price, reversal.Amount, reversal.Type, reversal.OriginalTransactionID, reversal.Reason))
+	if err != nil {
+		return Transaction{}, translateError(err, "transaction")
+	}
+
+	if !createdReversal.Amount.IsZero() {
+		if _, err := postBalance(ctx, s.inTx(tx), transactionEntry(createdReversal)); err != nil {
+			return Transaction{}, err
+		}
+	}
+	if _, err := moveStock(ctx, s.inTx(tx), saleMovement(createdReversal, 0)); err != nil {
+		return Transaction{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Transaction{}, translateError(err, "transaction")
+	}
+	return createdReversal, nil
+}
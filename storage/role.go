@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+// GetRolePermissions returns the permissions granted by any of roles, sorted.
+// Unknown roles grant nothing.
+func (s *PostgresStore) GetRolePermissions(ctx context.Context, roles []string) ([]string, error) {
+	rows, err := s.conn().QueryContext(ctx, "SELECT DISTINCT permission FROM tbl_role_permission WHERE role = ANY($1) ORDER BY permission", pq.Array(roles))
+	if err != nil {
+		return nil, translateError(err, "role")
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, translateError(err, "role")
+		}
+		permissions = append(permissions, permission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, "role")
+	}
+	return permissions, nil
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds of stock movements. Sales and returns of sales are booked with the
+// transaction they belong to; restocks and adjustments are booked by hand.
+const (
+	StockSale       = "sale"
+	StockRestock    = "restock"
+	StockAdjustment = "adjustment"
+	StockReturn     = "return"
+)
+
+// StockMovement is an entry of the stock ledger of an item. Qty is the signed
+// change of the stock, StockAfter the stock on hand once it was applied.
+// TransactionID links sales and returns to their transaction.
+type StockMovement struct {
+	ID            int
+	ItemID        int
+	Kind          string
+	Qty           int
+	StockAfter    int
+	TransactionID *int
+	Reason        string
+	CreatedAt     time.Time
+}
+
+var (
+	ErrInsufficientStock    = newError(ErrValidation, "insufficient_stock", "insufficient stock and the item does not allow backorders")
+	ErrInvalidStockMovement = newError(ErrValidation, "invalid_stock_movement", "restocks and returns must add stock and adjustments need a reason")
+)
+
+// checkStockMovement validates a movement booked by hand.
+func checkStockMovement(movement StockMovement) error {
+	switch movement.Kind {
+	case StockRestock, StockReturn:
+		if movement.Qty <= 0 {
+			return ErrInvalidStockMovement
+		}
+	case StockAdjustment:
+		if movement.Qty == 0 || movement.Reason == "" {
+			return ErrInvalidStockMovement
+		}
+	default:
+		return ErrInvalidStockMovement
+	}
+	return nil
+}
+
+const stockMovementColumns = "id, item_id, kind, qty, stock_after, transaction_id, reason, created_at"
+
+func scanStockMovement(row interface{ Scan(...interface{}) error }) (StockMovement, error) {
+	var movement StockMovement
+	err := row.Scan(&movement.ID, &movement.ItemID, &movement.Kind, &movement.Qty, &movement.StockAfter, &movement.TransactionID, &movement.Reason, &movement.CreatedAt)
+	return movement, err
+}
+
+// moveStock applies movement to the stock of an item and books it in the
+// ledger. Taking stock below zero fails with ErrInsufficientStock unless the
+// item allows backorders. Movements booked by hand need a live item, those of
+// a transaction follow it even when the item was deleted since. q must be a
+// transaction, which holds the lock on the item until it ends.
+func moveStock(ctx context.Context, q queryer, movement StockMovement) (StockMovement, error) {
+	var stock int
+	var allowBackorder, deleted bool
+	err := q.QueryRowContext(ctx, "SELECT stock, allow_backorder, deleted_at IS NOT NULL FROM tbl_items WHERE id = $1 FOR UPDATE", movement.ItemID).
+		Scan(&stock, &allowBackorder, &deleted)
+	if err != nil {
+		return StockMovement{}, translateError(err, "item")
+	}
+	if deleted && movement.TransactionID == nil {
+		return StockMovement{}, NotFoundError("item")
+	}
+	if movement.Qty < 0 && stock+movement.Qty < 0 && !allowBackorder {
+		return StockMovement{}, ErrInsufficientStock
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE tbl_items SET stock = stock + $1 WHERE id = $2", movement.Qty, movement.ItemID); err != nil {
+		return StockMovement{}, translateError(err, "item")
+	}
+	bookedMovement, err := scanStockMovement(q.QueryRowContext(ctx, "INSERT INTO tbl_stock_movements (item_id, kind, qty, stock_after, transaction_id, reason) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+stockMovementColumns,
+		movement.ItemID, movement.Kind, movement.Qty, stock+movement.Qty, movement.TransactionID, movement.Reason))
+	if err != nil {
+		return StockMovement{}, translateError(err, "stock_movement")
+	}
+	return bookedMovement, nil
+}
+
+// saleMovement is the movement that changes the quantity sold by a
+// transaction from oldQty to newQty: a sale of the difference, or a return
+// when the quantity went down.
+func saleMovement(transaction Transaction, oldQty int) StockMovement {
+	kind := StockSale
+	if transaction.Qty < oldQty {
+		kind = StockReturn
+	}
+	id := transaction.ID
+	return StockMovement{ItemID: transaction.ItemID, Kind: kind, Qty: oldQty - transaction.Qty, TransactionID: &id}
+}
+
+func (s *PostgresStore) AddStockMovement(ctx context.Context, movement StockMovement) (StockMovement, error) {
+	if err := checkStockMovement(movement); err != nil {
+		return StockMovement{}, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StockMovement{}, translateError(err, "stock_movement")
+	}
+	defer tx.Rollback()
+
+	movement.TransactionID = nil
+	bookedMovement, err := moveStock(ctx, s.inTx(tx), movement)
+	if err != nil {
+		return StockMovement{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return StockMovement{}, translateError(err, "stock_movement")
+	}
+	return bookedMovement, nil
+}
+
+func (s *PostgresStore) GetStockMovements(ctx context.Context, itemID int, page PageRequest) (Page[StockMovement], error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return Page[StockMovement]{}, translateError(err, "stock_movement")
+	}
+	var exists bool
+	if err := s.conn().QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM tbl_items WHERE id = $1)", itemID).Scan(&exists); err != nil {
+		return Page[StockMovement]{}, translateError(err, "item")
+	}
+	if !exists {
+		return Page[StockMovement]{}, NotFoundError("item")
+	}
+
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+stockMovementColumns+" FROM tbl_stock_movements WHERE item_id = $1 AND id > $2 ORDER BY id LIMIT $3", itemID, after.ID, page.limitClause())
+	if err != nil {
+		return Page[StockMovement]{}, translateError(err, "stock_movement")
+	}
+	defer rows.Close()
+
+	var movements []StockMovement
+	for rows.Next() {
+		movement, err := scanStockMovement(rows)
+		if err != nil {
+			return Page[StockMovement]{}, translateError(err, "stock_movement")
+		}
+		movements = append(movements, movement)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[StockMovement]{}, translateError(err, "stock_movement")
+	}
+	return newPage(movements, page, func(m StockMovement) cursor { return cursor{ID: m.ID} }), nil
+}
@@ -0,0 +1,99 @@
+// Package storagetest runs tests against every store implementation: the
+// memory store and, when APP_TEST_DB_DSN names a PostgreSQL database, the
+// Postgres store. The database is migrated and emptied for every test, so it
+// must not hold data anyone needs.
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+
+	"lesson/db"
+	"lesson/storage"
+)
+
+// DSNEnv is the environment variable holding the data source name of the
+// PostgreSQL database tests may use, e.g.
+// "postgres://postgres@localhost/lesson_test?sslmode=disable".
+const DSNEnv = "APP_TEST_DB_DSN"
+
+// Store is every store interface, as both implementations provide them.
+type Store interface {
+	storage.CustomerStore
+	storage.ItemStore
+	storage.TransactionStore
+	storage.OrderStore
+	storage.BalanceStore
+	storage.StockStore
+	storage.APIKeyStore
+	storage.RoleStore
+	storage.IdempotencyStore
+}
+
+// truncated lists the tables emptied before every test. Roles and their
+// permissions are seeded by the migrations and kept.
+const truncated = "tbl_customer, tbl_items, tbl_transaction, tbl_transaction_incomplete, tbl_orders, tbl_balance_entries, tbl_stock_movements, tbl_api_key, tbl_idempotency_key"
+
+// lockID is the advisory lock that keeps the tests of different packages,
+// which go test runs in parallel, from emptying the tables under each other.
+const lockID = 7310001
+
+var migrate sync.Once
+
+// Run runs test as a subtest once for every store, each time on empty
+// tables. The Postgres subtest is skipped unless DSNEnv is set.
+func Run(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Helper()
+	t.Run("memory", func(t *testing.T) {
+		test(t, storage.NewMemoryStore())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv(DSNEnv)
+		if dsn == "" {
+			t.Skip(DSNEnv + " is not set")
+		}
+		test(t, postgres(t, dsn))
+	})
+}
+
+// postgres returns a store on the empty database dsn names. It holds lockID
+// until the test ends.
+func postgres(t *testing.T, dsn string) *storage.PostgresStore {
+	t.Helper()
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var migrateErr error
+	migrate.Do(func() {
+		var migrator *db.Migrator
+		if migrator, migrateErr = db.NewMigrator(conn); migrateErr == nil {
+			migrateErr = migrator.Up()
+		}
+	})
+	if migrateErr != nil {
+		t.Fatal("migrating the test database: ", migrateErr)
+	}
+
+	ctx := context.Background()
+	lock, err := conn.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lock.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		lock.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockID)
+		lock.Close()
+	})
+	if _, err := lock.ExecContext(ctx, "TRUNCATE "+truncated+" RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatal(err)
+	}
+	return storage.NewPostgresStore(conn, 0)
+}
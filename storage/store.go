@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Every store method takes the request context, which bounds the database
+// work it does. The stores soft-delete rows: deleted rows keep their data
+// with DeletedAt set and are hidden from reads unless includeDeleted is true.
+type CustomerStore interface {
+	CreateCustomer(ctx context.Context, customer Customer) (Customer, error)
+	UpdateCustomer(ctx context.Context, customer Customer) (Customer, error)
+	PatchCustomer(ctx context.Context, id int, patch CustomerPatch) (Customer, error)
+	DeleteCustomer(ctx context.Context, id int) (Customer, error)
+	RestoreCustomer(ctx context.Context, id int) (Customer, error)
+	GetCustomer(ctx context.Context, id int, includeDeleted bool) (Customer, error)
+	GetCustomers(ctx context.Context, page PageRequest, includeDeleted bool) (Page[Customer], error)
+}
+
+type ItemStore interface {
+	CreateItem(ctx context.Context, item Item) (Item, error)
+	UpdateItem(ctx context.Context, item Item) (Item, error)
+	PatchItem(ctx context.Context, id int, patch ItemPatch) (Item, error)
+	DeleteItem(ctx context.Context, id int) (Item, error)
+	RestoreItem(ctx context.Context, id int) (Item, error)
+	GetItem(ctx context.Context, id int, includeDeleted bool) (Item, error)
+	GetItems(ctx context.Context, page PageRequest, includeDeleted bool) (Page[Item], error)
+}
+
+type TransactionStore interface {
+	GetTransactions(ctx context.Context, page PageRequest, includeDeleted bool) (Page[Transaction], error)
+	// GetCustomerTransactions and GetItemTransactions list the transactions
+	// of one customer or item. They fail with not found when it does not
+	// exist, or is deleted and includeDeleted is false.
+	GetCustomerTransactions(ctx context.Context, customerID int, page PageRequest, includeDeleted bool) (Page[Transaction], error)
+	GetItemTransactions(ctx context.Context, itemID int, page PageRequest, includeDeleted bool) (Page[Transaction], error)
+	CreateTransaction(ctx context.Context, transaction Transaction) (Transaction, error)
+	UpdateTransaction(ctx context.Context, transaction Transaction) (Transaction, error)
+	PatchTransaction(ctx context.Context, id int, patch TransactionPatch) (Transaction, error)
+	// DeleteTransaction only hides a transaction; it does not touch balances
+	// or stock. VoidTransaction and RefundTransaction reverse a sale instead
+	// and return the void or refund they record.
+	DeleteTransaction(ctx context.Context, id int) error
+	VoidTransaction(ctx context.Context, id int, reason string) (Transaction, error)
+	RefundTransaction(ctx context.Context, id int, qty int, reason string) (Transaction, error)
+	RestoreTransaction(ctx context.Context, id int) (Transaction, error)
+	GetTransaction(ctx context.Context, id int, includeDeleted bool) (Transaction, error)
+	GetTransactionDetailsWithCustomerAndItem(ctx context.Context, page PageRequest) (Page[TransactionView], error)
+	FilterTransactions(ctx context.Context, filter TransactionFilter, page PageRequest) (Page[TransactionView], error)
+}
+
+// OrderStore records purchases of several items at once. The lines of an
+// order are sales, which TransactionStore lists, voids and refunds.
+type OrderStore interface {
+	// CreateOrder books every line or none: a missing item, or a line that
+	// runs out of stock, fails with a *LineError naming the line.
+	CreateOrder(ctx context.Context, order Order) (Order, error)
+	GetOrder(ctx context.Context, id int) (Order, error)
+	// GetCustomerOrders lists the orders of a customer, deleted or not.
+	GetCustomerOrders(ctx context.Context, customerID int, page PageRequest) (Page[Order], error)
+}
+
+// BalanceStore keeps the balance ledger of customers, the only way their
+// balance changes. Transactions post their purchases themselves;
+// AddBalanceEntry posts top-ups and adjustments by hand.
+type BalanceStore interface {
+	// AddBalanceEntry fails with ErrInvalidBalanceEntry unless top-ups add to
+	// the balance and adjustments give a reason, and with
+	// ErrInsufficientBalance when the balance would go negative.
+	AddBalanceEntry(ctx context.Context, entry BalanceEntry) (BalanceEntry, error)
+	// GetBalanceStatement lists the entries of a customer, deleted or not,
+	// posted from from up to, but not including, to.
+	GetBalanceStatement(ctx context.Context, customerID int, from, to time.Time) (BalanceStatement, error)
+}
+
+// StockStore keeps the stock ledger of items. Transactions book their sales
+// and returns themselves; AddStockMovement books restocks, returns and
+// adjustments by hand.
+type StockStore interface {
+	// AddStockMovement fails with ErrInvalidStockMovement unless restocks and
+	// returns add stock and adjustments give a reason, and with
+	// ErrInsufficientStock when the stock would go negative on an item that
+	// does not allow backorders.
+	AddStockMovement(ctx context.Context, movement StockMovement) (StockMovement, error)
+	// GetStockMovements lists the movements of an item, deleted or not, oldest
+	// first.
+	GetStockMovements(ctx context.Context, itemID int, page PageRequest) (Page[StockMovement], error)
+}
+
+// APIKeyStore keeps API keys. Revoked keys stay listed but no longer
+// authenticate.
+type APIKeyStore interface {
+	// CreateAPIKey fails with not found when key.Role does not exist.
+	CreateAPIKey(ctx context.Context, key APIKey) (APIKey, error)
+	GetAPIKeys(ctx context.Context, includeRevoked bool) ([]APIKey, error)
+	// GetAPIKeyByPrefix returns the key with the given prefix, revoked or not.
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int) (APIKey, error)
+	// TouchAPIKey records that the key was used. The timestamp is only
+	// moved once it is a minute old.
+	TouchAPIKey(ctx context.Context, id int) error
+}
+
+// RoleStore maps roles to the permissions they grant. Permissions are named
+// like "customers:delete"; the auth package declares them.
+type RoleStore interface {
+	GetRolePermissions(ctx context.Context, roles []string) ([]string, error)
+}
+
+// IdempotencyStore keeps the responses to requests sent with an
+// Idempotency-Key until they expire.
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey claims record.Key for a request that is about to
+	// be processed and reports true, unless the key is in use and has not
+	// expired; then it returns the stored record and false.
+	ReserveIdempotencyKey(ctx context.Context, record IdempotencyRecord, ttl time.Duration) (IdempotencyRecord, bool, error)
+	// CompleteIdempotencyKey stores the response to a reserved key.
+	CompleteIdempotencyKey(ctx context.Context, record IdempotencyRecord) error
+	// ReleaseIdempotencyKey frees a reserved key without a response, so that
+	// the request can be retried.
+	ReleaseIdempotencyKey(ctx context.Context, principal, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+}
+
+// PostgresStore implements CustomerStore, ItemStore, TransactionStore,
+// OrderStore, BalanceStore, StockStore, APIKeyStore, RoleStore and
+// IdempotencyStore on top of a PostgreSQL database.
+type PostgresStore struct {
+	db        *sql.DB
+	slowQuery time.Duration
+}
+
+// NewPostgresStore returns a store on db that logs statements running longer
+// than slowQuery. A zero slowQuery disables the log.
+func NewPostgresStore(db *sql.DB, slowQuery time.Duration) *PostgresStore {
+	return &PostgresStore{db: db, slowQuery: slowQuery}
+}
+
+// conn returns the connection pool wrapped for slow query logging.
+func (s *PostgresStore) conn() queryer {
+	return slowQueryLogger{q: s.db, threshold: s.slowQuery}
+}
+
+// inTx returns tx wrapped for slow query logging.
+func (s *PostgresStore) inTx(tx *sql.Tx) queryer {
+	return slowQueryLogger{q: tx, threshold: s.slowQuery}
+}
+
+var (
+	_ CustomerStore    = (*PostgresStore)(nil)
+	_ ItemStore        = (*PostgresStore)(nil)
+	_ TransactionStore = (*PostgresStore)(nil)
+	_ OrderStore       = (*PostgresStore)(nil)
+	_ BalanceStore     = (*PostgresStore)(nil)
+	_ StockStore       = (*PostgresStore)(nil)
+	_ APIKeyStore      = (*PostgresStore)(nil)
+	_ RoleStore        = (*PostgresStore)(nil)
+	_ IdempotencyStore = (*PostgresStore)(nil)
+)
@@ -1,152 +1,359 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 )
 
+// Types of transactions. Sales are the lines of the order OrderID points at.
+// Voids and refunds reverse a sale, which OriginalTransactionID points at,
+// and carry a negative Qty and Amount.
+const (
+	TransactionSale   = "sale"
+	TransactionVoid   = "void"
+	TransactionRefund = "refund"
+)
+
 type Transaction struct {
-	ID         int
-	CustomerID int
-	ItemID     int
-	Qty        int
-	Amount     float64
-	CreatedAt  string
-	UpdatedAt  string
-	DeletedAt  string
+	ID                    int
+	CustomerID            int
+	ItemID                int
+	Qty                   int
+	Amount                Money
+	Type                  string
+	OriginalTransactionID *int
+	Reason                string
+	OrderID               *int
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	DeletedAt             *time.Time
+	Version               int
 }
 
+// TransactionView is a row of the TransactionViews SQL view: a live,
+// non-deleted transaction joined with its customer and item. Price is the
+// item's unit price at the time of sale.
 type TransactionView struct {
-	ID           int        `json:"id"`
-	CustomerID   int        `json:"customer_id"`
-	CustomerName string     `json:"customer_name"`
-	ItemID       int        `json:"item_id"`
-	ItemName     string     `json:"item_name"`
-	Qty          int        `json:"qty"`
-	Price        float64    `json:"price"`
-	Amount       float64    `json:"amount"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
-	DeletedAt    *time.Time `json:"deleted_at"`
+	ID                    int
+	CustomerID            int
+	CustomerName          string
+	ItemID                int
+	ItemName              string
+	Qty                   int
+	Price                 Money
+	Amount                Money
+	Type                  string
+	OriginalTransactionID *int
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	DeletedAt             *time.Time
+}
+
+// TransactionPatch lists the columns a partial update changes; nil fields
+// keep their stored value. A non-zero Version must match the stored version.
+type TransactionPatch struct {
+	Qty     *int
+	Version int
+}
+
+// transactionColumns are scanned by scanTransaction. A row that was never
+// updated reports its creation time as updated_at.
+const transactionColumns = "id, customer_id, item_id, qty, amount, type, original_transaction_id, reason, order_id, created_at, COALESCE(updated_at, created_at), deleted_at, version"
+
+func scanTransaction(row interface{ Scan(...interface{}) error }) (Transaction, error) {
+	var transaction Transaction
+	err := row.Scan(&transaction.ID, &transaction.CustomerID, &transaction.ItemID, &transaction.Qty, &transaction.Amount, &transaction.Type, &transaction.OriginalTransactionID, &transaction.Reason, &transaction.OrderID, &transaction.CreatedAt, &transaction.UpdatedAt, &transaction.DeletedAt, &transaction.Version)
+	return transaction, err
+}
+
+// transactionViewColumns are scanned by scanTransactionView.
+const transactionViewColumns = "id, customer_id, customer_name, item_id, item_name, qty, price, amount, type, original_transaction_id, created_at, updated_at"
+
+func scanTransactionView(row interface{ Scan(...interface{}) error }) (TransactionView, error) {
+	var view TransactionView
+	err := row.Scan(&view.ID, &view.CustomerID, &view.CustomerName, &view.ItemID, &view.ItemName, &view.Qty, &view.Price, &view.Amount, &view.Type, &view.OriginalTransactionID, &view.CreatedAt, &view.UpdatedAt)
+	return view, err
 }
 
-func GetTransactions(db *sql.DB) ([]Transaction, error) {
-	rows, err := db.Query("SELECT id, customer_id, item_id, qty, amount, created_at, updated_at FROM tbl_transaction")
+func (s *PostgresStore) GetTransactions(ctx context.Context, page PageRequest, includeDeleted bool) (Page[Transaction], error) {
+	after, err := decodeCursor(page.Cursor)
 	if err != nil {
-		return nil, err
+		return Page[Transaction]{}, translateError(err, "transaction")
+	}
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+transactionColumns+" FROM tbl_transaction WHERE ($1 OR deleted_at IS NULL) AND id > $2 ORDER BY id LIMIT $3", includeDeleted, after.ID, page.limitClause())
+	if err != nil {
+		return Page[Transaction]{}, translateError(err, "transaction")
 	}
 	defer rows.Close()
 
 	var transactions []Transaction
 
 	for rows.Next() {
-		var transaction Transaction
-		err := rows.Scan(&transaction.ID, &transaction.CustomerID, &transaction.ItemID, &transaction.Qty, &transaction.Amount, &transaction.CreatedAt, &transaction.UpdatedAt)
+		transaction, err := scanTransaction(rows)
 		if err != nil {
-			return nil, err
+			return Page[Transaction]{}, translateError(err, "transaction")
 		}
 		transactions = append(transactions, transaction)
 	}
 
-	return transactions, nil
+	if err := rows.Err(); err != nil {
+		return Page[Transaction]{}, translateError(err, "transaction")
+	}
+
+	return newPage(transactions, page, func(t Transaction) cursor { return cursor{ID: t.ID} }), nil
 }
 
-func CreateTransaction(db *sql.DB, transaction Transaction) (Transaction, error) {
-	var createdTransaction Transaction
-	err := db.QueryRow("INSERT INTO tbl_transaction (customer_id, item_id, qty, amount) VALUES ($1, $2, $3, $4) RETURNING id, customer_id, item_id, qty, amount, created_at, updated_at",
-		transaction.CustomerID, transaction.ItemID, transaction.Qty, transaction.Amount).
-		Scan(&createdTransaction.ID, &createdTransaction.CustomerID, &createdTransaction.ItemID, &createdTransaction.Qty, &createdTransaction.Amount, &createdTransaction.CreatedAt, &createdTransaction.UpdatedAt)
+func (s *PostgresStore) GetCustomerTransactions(ctx context.Context, customerID int, page PageRequest, includeDeleted bool) (Page[Transaction], error) {
+	return s.getOwnedTransactions(ctx, "customer", "tbl_customer", "customer_id", customerID, page, includeDeleted)
+}
+
+func (s *PostgresStore) GetItemTransactions(ctx context.Context, itemID int, page PageRequest, includeDeleted bool) (Page[Transaction], error) {
+	return s.getOwnedTransactions(ctx, "item", "tbl_items", "item_id", itemID, page, includeDeleted)
+}
+
+// getOwnedTransactions lists the transactions whose column references the
+// row id of table, after checking that the row is visible.
+func (s *PostgresStore) getOwnedTransactions(ctx context.Context, entity, table, column string, id int, page PageRequest, includeDeleted bool) (Page[Transaction], error) {
+	after, err := decodeCursor(page.Cursor)
 	if err != nil {
-		return Transaction{}, err
+		return Page[Transaction]{}, translateError(err, "transaction")
+	}
+	var exists bool
+	err = s.conn().QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+table+" WHERE id = $1 AND ($2 OR deleted_at IS NULL))", id, includeDeleted).Scan(&exists)
+	if err != nil {
+		return Page[Transaction]{}, translateError(err, entity)
+	}
+	if !exists {
+		return Page[Transaction]{}, NotFoundError(entity)
+	}
+
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+transactionColumns+" FROM tbl_transaction WHERE "+column+" = $1 AND ($2 OR deleted_at IS NULL) AND id > $3 ORDER BY id LIMIT $4", id, includeDeleted, after.ID, page.limitClause())
+	if err != nil {
+		return Page[Transaction]{}, translateError(err, "transaction")
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return Page[Transaction]{}, translateError(err, "transaction")
+		}
+		transactions = append(transactions, transaction)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[Transaction]{}, translateError(err, "transaction")
+	}
+
+	return newPage(transactions, page, func(t Transaction) cursor { return cursor{ID: t.ID} }), nil
+}
+
+var (
+	ErrInvalidQuantity     = newError(ErrValidation, "invalid_quantity", "quantity must be greater than zero")
+	ErrInsufficientBalance = newError(ErrValidation, "insufficient_balance", "insufficient customer balance")
+	ErrNoUnitPrice         = newError(ErrValidation, "no_unit_price", "the sale has no unit price and no units to derive one from")
+)
+
+// CreateTransaction records a purchase of transaction.Qty units of an item by
+// a customer as an order of one line. The amount is computed from the item's
+// current price, so the client-supplied Amount is ignored. The purchase is
+// posted to the customer's balance ledger and the sale booked in the stock
+// ledger in the same database transaction as the insert.
+func (s *PostgresStore) CreateTransaction(ctx context.Context, transaction Transaction) (Transaction, error) {
+	_, sales, err := s.createOrder(ctx, Order{CustomerID: transaction.CustomerID, Lines: []OrderLine{{ItemID: transaction.ItemID, Qty: transaction.Qty}}})
+	if err != nil {
+		return Transaction{}, singleLine(err)
 	}
-	return createdTransaction, nil
+	return sales[0], nil
 }
 
-func UpdateTransaction(db *sql.DB, transaction Transaction) (Transaction, error) {
-	_, err := db.Exec("UPDATE tbl_transaction SET qty = $1, amount = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3", transaction.Qty, transaction.Amount, transaction.ID)
+// UpdateTransaction overwrites the quantity of a live transaction and returns
+// the updated row. The amount is recomputed with amountFor. A non-zero
+// transaction.Version must match the stored version, otherwise the update
+// reports StaleVersionError.
+func (s *PostgresStore) UpdateTransaction(ctx context.Context, transaction Transaction) (Transaction, error) {
+	return s.changeTransaction(ctx, transaction.ID, &transaction.Qty, transaction.Version)
+}
+
+// PatchTransaction applies patch to a live transaction and returns the
+// updated row.
+func (s *PostgresStore) PatchTransaction(ctx context.Context, id int, patch TransactionPatch) (Transaction, error) {
+	return s.changeTransaction(ctx, id, patch.Qty, patch.Version)
+}
+
+// amountFor returns what qty units of sale cost: qty times its unit price.
+// Sales recorded before unit prices were kept have none, so their amount is
+// scaled to qty instead.
+func (sale lockedSale) amountFor(qty int) (Money, error) {
+	switch {
+	case sale.priced:
+		return sale.price.MulInt(qty), nil
+	case qty == sale.Qty:
+		return sale.Amount, nil
+	case sale.Qty == 0:
+		return Money{}, ErrNoUnitPrice
+	}
+	return sale.Amount.MulRatio(qty, sale.Qty), nil
+}
+
+// changeTransaction locks the live transaction id, sets its quantity to qty,
+// unless qty is nil, and its amount to match, posts the change of the amount
+// to the customer's balance and books a sale or return in the stock ledger
+// when the quantity changed, all in one database transaction. A change the
+// balance cannot cover fails with ErrInsufficientBalance. Only sales that
+// were not voided or refunded can be changed. A non-zero version must match
+// the stored one.
+func (s *PostgresStore) changeTransaction(ctx context.Context, id int, qty *int, version int) (Transaction, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Transaction{}, translateError(err, "transaction")
+	}
+	defer tx.Rollback()
+
+	original, err := lockSale(ctx, s.inTx(tx), id)
 	if err != nil {
 		return Transaction{}, err
 	}
-	return transaction, nil
+	if original.reversedQty != 0 {
+		return Transaction{}, ErrTransactionReversed
+	}
+	newQty := original.Qty
+	if qty != nil {
+		newQty = *qty
+	}
+	newAmount, err := original.amountFor(newQty)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	changedTransaction, err := scanTransaction(s.inTx(tx).QueryRowContext(ctx, "UPDATE tbl_transaction SET qty = $1, amount = $2, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $3 AND ($4 = 0 OR version = $4) RETURNING "+transactionColumns,
+		newQty, newAmount, id, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Transaction{}, StaleVersionError("transaction")
+	}
+	if err != nil {
+		return Transaction{}, translateError(err, "transaction")
+	}
+
+	if entry := saleChangeEntry(changedTransaction, original.Amount); !entry.Amount.IsZero() {
+		if _, err := postBalance(ctx, s.inTx(tx), entry); err != nil {
+			return Transaction{}, err
+		}
+	}
+	if changedTransaction.Qty != original.Qty {
+		if _, err := moveStock(ctx, s.inTx(tx), saleMovement(changedTransaction, original.Qty)); err != nil {
+			return Transaction{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Transaction{}, translateError(err, "transaction")
+	}
+	return changedTransaction, nil
 }
 
-func DeleteTransaction(db *sql.DB, id int) error {
-	_, err := db.Exec("UPDATE tbl_transaction SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+// DeleteTransaction soft-deletes a transaction. Deleting a missing or already
+// deleted transaction reports not found.
+func (s *PostgresStore) DeleteTransaction(ctx context.Context, id int) error {
+	res, err := s.conn().ExecContext(ctx, "UPDATE tbl_transaction SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL", id)
 	if err != nil {
-		return err
+		return translateError(err, "transaction")
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return translateError(err, "transaction")
+	} else if n == 0 {
+		return NotFoundError("transaction")
 	}
 	return nil
 }
 
-func GetTransaction(db *sql.DB, id int) (Transaction, error) {
-	var transaction Transaction
-	err := db.QueryRow("SELECT id, customer_id, item_id, qty, amount, created_at, updated_at FROM tbl_transaction WHERE id = $1", id).
-		Scan(&transaction.ID, &transaction.CustomerID, &transaction.ItemID, &transaction.Qty, &transaction.Amount, &transaction.CreatedAt, &transaction.UpdatedAt)
+// RestoreTransaction undoes DeleteTransaction. Restoring a transaction that is
+// not deleted is a conflict.
+func (s *PostgresStore) RestoreTransaction(ctx context.Context, id int) (Transaction, error) {
+	restoredTransaction, err := scanTransaction(s.conn().QueryRowContext(ctx, "UPDATE tbl_transaction SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL RETURNING "+transactionColumns, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.GetTransaction(ctx, id, false); err != nil {
+			return Transaction{}, err
+		}
+		return Transaction{}, NotDeletedError("transaction")
+	}
 	if err != nil {
-		return Transaction{}, err
+		return Transaction{}, translateError(err, "transaction")
+	}
+	return restoredTransaction, nil
+}
+
+func (s *PostgresStore) GetTransaction(ctx context.Context, id int, includeDeleted bool) (Transaction, error) {
+	transaction, err := scanTransaction(s.conn().QueryRowContext(ctx, "SELECT "+transactionColumns+" FROM tbl_transaction WHERE id = $1 AND ($2 OR deleted_at IS NULL)", id, includeDeleted))
+	if err != nil {
+		return Transaction{}, translateError(err, "transaction")
 	}
 	return transaction, nil
 }
 
-func GetTransactionDetailsWithCustomerAndItem(db *sql.DB) ([]TransactionView, error) {
-	rows, err := db.Query("SELECT tv.id, tv.customer_id, c.customer_name, tv.item_id, i.item_name, tv.qty, tv.price, tv.amount, tv.created_at, tv.updated_at FROM TransactionViews tv INNER JOIN tbl_customer c ON tv.customer_id = c.id INNER JOIN tbl_items i ON tv.item_id = i.id")
+func (s *PostgresStore) GetTransactionDetailsWithCustomerAndItem(ctx context.Context, page PageRequest) (Page[TransactionView], error) {
+	after, err := decodeCursor(page.Cursor)
 	if err != nil {
-		return nil, err
+		return Page[TransactionView]{}, translateError(err, "transaction")
+	}
+	rows, err := s.conn().QueryContext(ctx, "SELECT "+transactionViewColumns+" FROM TransactionViews WHERE id > $1 ORDER BY id LIMIT $2", after.ID, page.limitClause())
+	if err != nil {
+		return Page[TransactionView]{}, translateError(err, "transaction")
 	}
 	defer rows.Close()
 
 	var transactions []TransactionView
 
 	for rows.Next() {
-		var transaction TransactionView
-		if err := rows.Scan(&transaction.ID, &transaction.CustomerID, &transaction.CustomerName, &transaction.ItemID, &transaction.ItemName, &transaction.Qty, &transaction.Price, &transaction.Amount, &transaction.CreatedAt, &transaction.UpdatedAt); err != nil {
-			return nil, err
+		transaction, err := scanTransactionView(rows)
+		if err != nil {
+			return Page[TransactionView]{}, translateError(err, "transaction")
 		}
 		transactions = append(transactions, transaction)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return Page[TransactionView]{}, translateError(err, "transaction")
 	}
 
-	return transactions, nil
+	return newPage(transactions, page, func(t TransactionView) cursor { return cursor{ID: t.ID} }), nil
 }
 
-func FilterTransactions(db *sql.DB, id int, customerName, itemName string) ([]TransactionView, error) {
-	query := "SELECT tv.id, tv.customer_id, c.customer_name, tv.item_id, i.item_name, tv.qty, tv.price, tv.amount, tv.created_at, tv.updated_at FROM TransactionViews tv INNER JOIN tbl_customer c ON tv.customer_id = c.id INNER JOIN tbl_items i ON tv.item_id = i.id WHERE true"
-
-	if id != 0 {
-		query += fmt.Sprintf(" AND tv.id = %d", id)
+func (s *PostgresStore) FilterTransactions(ctx context.Context, filter TransactionFilter, page PageRequest) (Page[TransactionView], error) {
+	if err := filter.Validate(); err != nil {
+		return Page[TransactionView]{}, translateError(err, "transaction")
 	}
-
-	if customerName != "" {
-		query += fmt.Sprintf(" AND c.customer_name = '%s'", customerName)
+	after, err := filter.cursorView(page.Cursor)
+	if err != nil {
+		return Page[TransactionView]{}, translateError(err, "transaction")
 	}
 
-	if itemName != "" {
-		query += fmt.Sprintf(" AND i.item_name = '%s'", itemName)
-	}
+	where, args := filter.whereClause(after)
+	args = append(args, page.limitClause())
+	query := "SELECT " + transactionViewColumns + " FROM TransactionViews" + where + " ORDER BY " + filter.orderBy() + fmt.Sprintf(" LIMIT $%d", len(args))
 
-	rows, err := db.Query(query)
+	rows, err := s.conn().QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return Page[TransactionView]{}, translateError(err, "transaction")
 	}
 	defer rows.Close()
 
 	var transactions []TransactionView
 
 	for rows.Next() {
-		var transaction TransactionView
-		if err := rows.Scan(&transaction.ID, &transaction.CustomerID, &transaction.CustomerName, &transaction.ItemID, &transaction.ItemName, &transaction.Qty, &transaction.Price, &transaction.Amount, &transaction.CreatedAt, &transaction.UpdatedAt); err != nil {
-			return nil, err
+		transaction, err := scanTransactionView(rows)
+		if err != nil {
+			return Page[TransactionView]{}, translateError(err, "transaction")
 		}
 		transactions = append(transactions, transaction)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return Page[TransactionView]{}, translateError(err, "transaction")
 	}
 
-	return transactions, nil
+	return newPage(transactions, page, filter.cursorOf), nil
 }
@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TransactionFilter narrows down the rows returned by FilterTransactions.
+// Zero values and nil pointers leave the corresponding condition out.
+type TransactionFilter struct {
+	ID          int
+	CustomerIDs []int
+	ItemIDs     []int
+
+	// CustomerName and ItemName match case-insensitively anywhere in the name.
+	CustomerName string
+	ItemName     string
+
+	// CreatedFrom is inclusive and CreatedTo is exclusive.
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+
+	MinAmount *Money
+	MaxAmount *Money
+	MinQty    *int
+	MaxQty    *int
+
+	Sort  string
+	Order string
+}
+
+// InvalidParameterError reports a query parameter that cannot be applied.
+func InvalidParameterError(param, message string) *Error {
+	return InvalidArgumentError("invalid_parameter", fmt.Sprintf("invalid %s: %s", param, message))
+}
+
+// transactionSortColumns whitelists the columns of TransactionViews that
+// results may be sorted by. Prices and amounts recorded before they were
+// required may be NULL and sort as zero, the way they are scanned.
+var transactionSortColumns = map[string]string{
+	"id":            "id",
+	"customer_name": "customer_name",
+	"item_name":     "item_name",
+	"qty":           "qty",
+	"price":         "COALESCE(price, 0)",
+	"amount":        "COALESCE(amount, 0)",
+	"created_at":    "created_at",
+}
+
+// Validate checks the sort and order fields and the consistency of ranges.
+func (f TransactionFilter) Validate() error {
+	if f.Sort != "" {
+		if _, ok := transactionSortColumns[f.Sort]; !ok {
+			return InvalidParameterError("sort", fmt.Sprintf("cannot sort by %q", f.Sort))
+		}
+	}
+	switch strings.ToLower(f.Order) {
+	case "", "asc", "desc":
+	default:
+		return InvalidParameterError("order", "must be asc or desc")
+	}
+	if f.CreatedFrom != nil && f.CreatedTo != nil && !f.CreatedFrom.Before(*f.CreatedTo) {
+		return InvalidParameterError("created_to", "must be after created_from")
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil && f.MinAmount.Cmp(*f.MaxAmount) > 0 {
+		return InvalidParameterError("max_amount", "must not be less than min_amount")
+	}
+	if f.MinQty != nil && f.MaxQty != nil && *f.MinQty > *f.MaxQty {
+		return InvalidParameterError("max_qty", "must not be less than min_qty")
+	}
+	return nil
+}
+
+func (f TransactionFilter) sortColumn() string {
+	if f.Sort == "" {
+		return "id"
+	}
+	return transactionSortColumns[f.Sort]
+}
+
+func (f TransactionFilter) orderBy() string {
+	column := f.sortColumn()
+	direction := "ASC"
+	if strings.EqualFold(f.Order, "desc") {
+		direction = "DESC"
+	}
+	if column == "id" {
+		return "id " + direction
+	}
+	return column + " " + direction + ", id " + direction
+}
+
+// sortValue returns the value view has in the sort column.
+func (f TransactionFilter) sortValue(view TransactionView) interface{} {
+	switch f.Sort {
+	case "customer_name":
+		return view.CustomerName
+	case "item_name":
+		return view.ItemName
+	case "qty":
+		return view.Qty
+	case "price":
+		return view.Price
+	case "amount":
+		return view.Amount
+	case "created_at":
+		return view.CreatedAt
+	}
+	return view.ID
+}
+
+// cursorOf returns the cursor of the page after view. Key holds the value of
+// the sort column, as the text cursorView parses.
+func (f TransactionFilter) cursorOf(view TransactionView) cursor {
+	c := cursor{ID: view.ID}
+	switch value := f.sortValue(view).(type) {
+	case string:
+		c.Key = value
+	case int:
+		if f.Sort == "qty" {
+			c.Key = strconv.Itoa(value)
+		}
+	case Money:
+		c.Key = value.String()
+	case time.Time:
+		c.Key = value.Format(time.RFC3339Nano)
+	}
+	return c
+}
+
+// cursorView decodes s into a view that holds only the ID and sort column of
+// the last row of the previous page, which the next page starts after. The
+// zero view starts from the first row.
+func (f TransactionFilter) cursorView(s string) (TransactionView, error) {
+	c, err := decodeCursor(s)
+	if err != nil || c.ID == 0 {
+		return TransactionView{}, err
+	}
+	view := TransactionView{ID: c.ID}
+	switch f.Sort {
+	case "customer_name":
+		view.CustomerName = c.Key
+	case "item_name":
+		view.ItemName = c.Key
+	case "qty":
+		view.Qty, err = strconv.Atoi(c.Key)
+	case "price":
+		view.Price, err = ParseMoney(c.Key)
+	case "amount":
+		view.Amount, err = ParseMoney(c.Key)
+	case "created_at":
+		view.CreatedAt, err = time.Parse(time.RFC3339Nano, c.Key)
+	}
+	if err != nil {
+		return TransactionView{}, ErrInvalidCursor
+	}
+	return view, nil
+}
+
+// whereClause builds a parameterized WHERE clause for TransactionViews. It
+// leaves out the rows up to after in the sort order, unless after is the
+// zero view.
+func (f TransactionFilter) whereClause(after TransactionView) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.ID != 0 {
+		add("id = $%d", f.ID)
+	}
+	if len(f.CustomerIDs) > 0 {
+		add("customer_id = ANY($%d)", pq.Array(f.CustomerIDs))
+	}
+	if len(f.ItemIDs) > 0 {
+		add("item_id = ANY($%d)", pq.Array(f.ItemIDs))
+	}
+	if f.CustomerName != "" {
+		add("customer_name ILIKE $%d", "%"+escapeLike(f.CustomerName)+"%")
+	}
+	if f.ItemName != "" {
+		add("item_name ILIKE $%d", "%"+escapeLike(f.ItemName)+"%")
+	}
+	if f.CreatedFrom != nil {
+		add("created_at >= $%d", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		add("created_at < $%d", *f.CreatedTo)
+	}
+	if f.MinAmount != nil {
+		add("amount >= $%d", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		add("amount <= $%d", *f.MaxAmount)
+	}
+	if f.MinQty != nil {
+		add("qty >= $%d", *f.MinQty)
+	}
+	if f.MaxQty != nil {
+		add("qty <= $%d", *f.MaxQty)
+	}
+	if after.ID != 0 {
+		op := ">"
+		if strings.EqualFold(f.Order, "desc") {
+			op = "<"
+		}
+		if column := f.sortColumn(); column == "id" {
+			add("id "+op+" $%d", after.ID)
+		} else {
+			args = append(args, f.sortValue(after), after.ID)
+			conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d)", column, op, len(args)-1, len(args)))
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// matches reports whether view satisfies the filter. It is the in-memory
+// counterpart of whereClause.
+func (f TransactionFilter) matches(view TransactionView) bool {
+	switch {
+	case f.ID != 0 && view.ID != f.ID:
+		return false
+	case len(f.CustomerIDs) > 0 && !containsInt(f.CustomerIDs, view.CustomerID):
+		return false
+	case len(f.ItemIDs) > 0 && !containsInt(f.ItemIDs, view.ItemID):
+		return false
+	case f.CustomerName != "" && !containsFold(view.CustomerName, f.CustomerName):
+		return false
+	case f.ItemName != "" && !containsFold(view.ItemName, f.ItemName):
+		return false
+	case f.CreatedFrom != nil && view.CreatedAt.Before(*f.CreatedFrom):
+		return false
+	case f.CreatedTo != nil && !view.CreatedAt.Before(*f.CreatedTo):
+		return false
+	case f.MinAmount != nil && view.Amount.LessThan(*f.MinAmount):
+		return false
+	case f.MaxAmount != nil && f.MaxAmount.LessThan(view.Amount):
+		return false
+	case f.MinQty != nil && view.Qty < *f.MinQty:
+		return false
+	case f.MaxQty != nil && view.Qty > *f.MaxQty:
+		return false
+	}
+	return true
+}
+
+// less orders two views the way orderBy does.
+func (f TransactionFilter) less(a, b TransactionView) bool {
+	desc := strings.EqualFold(f.Order, "desc")
+	var cmp int
+	switch f.Sort {
+	case "customer_name":
+		cmp = strings.Compare(a.CustomerName, b.CustomerName)
+	case "item_name":
+		cmp = strings.Compare(a.ItemName, b.ItemName)
+	case "qty":
+		cmp = a.Qty - b.Qty
+	case "price":
+		cmp = a.Price.Cmp(b.Price)
+	case "amount":
+		cmp = a.Amount.Cmp(b.Amount)
+	case "created_at":
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if cmp == 0 {
+		cmp = a.ID - b.ID
+	}
+	if desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}